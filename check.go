@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+)
+
+var checkRules = []rule{
+	{ID: "undecryptable-value", Description: "The {cipher} value cannot be decrypted with the provided key"},
+}
+
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	var (
+		format  = fs.String("format", "text", "The report format: text, json or sarif.")
+		output  = fs.String("o", "-", `The file to write the report to. Use '-' for stdout.`)
+		keyFile = fs.String("k", "", keyFlagUsage)
+		config  = fs.String("config", "", configFlagUsage)
+		keyring stringsFlag
+	)
+	fs.Var(&keyring, "keyring", keyringFlagUsage)
+	kmsOptions := addKMSFlags(fs)
+	jasyptOptions := addJasyptFlags(fs)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s check [flags] <file or directory>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := validateReportFormat(*format); err != nil {
+		exitOnError("%v", err)
+	}
+
+	valueDecryptors, err := newValueDecryptors(fs, *config, fs.Arg(0), *keyFile, keyring, kmsOptions, jasyptOptions)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+
+	report := findingsReport{}
+	err = walkFiles(fs.Args(), func(path string, content []byte) error {
+		result, err := decryptor.NewSchemeDecryptor(valueDecryptors.schemes, decryptor.WithFileName(path)).Check(bytes.NewReader(content))
+		if err != nil {
+			return err
+		}
+		report.Files++
+		report.Values += result.Values
+		for fingerprint, count := range result.Keys {
+			if report.Keys == nil {
+				report.Keys = make(map[string]int)
+			}
+			report.Keys[fingerprint] += count
+		}
+		for _, failure := range result.Failures {
+			report.Findings = append(report.Findings, finding{
+				RuleID:   checkRules[0].ID,
+				Level:    "error",
+				File:     failure.File,
+				Line:     failure.Line,
+				Column:   failure.Column,
+				Path:     failure.Path,
+				KeyAlias: failure.KeyAlias,
+				Message:  (&decryptor.DecryptError{Path: failure.Path, KeyAlias: failure.KeyAlias, Err: failure.Err}).Error(),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		exitOnError("check error: %v", err)
+	}
+
+	out, closeOutput, err := openOutput(*output, true)
+	if err != nil {
+		exitOnError("output open file error: %v", err)
+	}
+	if err = writeFindings(out, *format, checkRules, report); err != nil {
+		exitOnError("report write error: %v", err)
+	}
+	closeOutput()
+	_, _ = fmt.Fprintf(os.Stderr, "checked %d values in %d files, %d failed\n", report.Values, report.Files, len(report.Findings))
+	valueDecryptors.writeKeyUsage(report.Keys)
+	if len(report.Findings) != 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/configtree"
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+)
+
+func runConfigTree(args []string) {
+	fs := flag.NewFlagSet("configtree", flag.ExitOnError)
+	var (
+		output        = fs.String("o", "", "The target directory, it is replaced atomically by a symlink to the written tree.")
+		naming        = fs.String("naming", string(configtree.NamingDotted), "The file naming: dotted (spring.datasource.password), nested (spring/datasource/password) or env (SPRING_DATASOURCE_PASSWORD).")
+		encryptedOnly = fs.Bool("encrypted-only", false, "Write only the properties which were encrypted.")
+		mode          = fs.String("mode", "0400", "The permission of the written files.")
+		keyFile       = fs.String("k", "", keyFlagUsage)
+		config        = fs.String("config", "", configFlagUsage)
+		keyring       stringsFlag
+	)
+	fs.Var(&keyring, "keyring", keyringFlagUsage)
+	kmsOptions := addKMSFlags(fs)
+	jasyptOptions := addJasyptFlags(fs)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s configtree [flags] -o <directory> <config file>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 || *output == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	treeNaming, err := configtree.ParseNaming(*naming)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	perm, err := strconv.ParseUint(*mode, 8, 32)
+	if err != nil || perm > 0777 {
+		exitOnError("invalid mode '%s', expected octal permission like 0400", *mode)
+	}
+	writer, err := configtree.NewWriter(configtree.WithNaming(treeNaming), configtree.WithPerm(os.FileMode(perm)))
+	if err != nil {
+		exitOnError("%v", err)
+	}
+
+	valueDecryptors, err := newValueDecryptors(fs, *config, fs.Arg(0), *keyFile, keyring, kmsOptions, jasyptOptions)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	valueDecryptor := decryptor.NewSchemeDecryptor(valueDecryptors.schemes)
+	props := make(properties.Properties)
+	for _, source := range fs.Args() {
+		content, err := ioutil.ReadFile(source)
+		if err != nil {
+			exitOnError("input read file error: %v", err)
+		}
+		sourceProps, err := properties.Read(bytes.NewReader(content), properties.FormatOf(source))
+		if err != nil {
+			exitOnError("%s: %v", source, err)
+		}
+		for _, key := range sourceProps.Keys() {
+			value := sourceProps[key]
+			if s, ok := value.(string); ok {
+				plainText, err := valueDecryptor.DecryptValue(s)
+				if err != nil {
+					exitOnError("%v", &decryptor.DecryptError{File: source, Path: key, Err: err})
+				}
+				if *encryptedOnly && plainText == s {
+					// a later plain value overrides an earlier encrypted one
+					delete(props, key)
+					continue
+				}
+				value = plainText
+			} else if *encryptedOnly {
+				delete(props, key)
+				continue
+			}
+			props[key] = value
+		}
+	}
+	if err = writer.Write(*output, props); err != nil {
+		exitOnError("configtree write error: %v", err)
+	}
+}
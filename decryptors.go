@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/encryptor"
+	_ "github.com/grepplabs/spring-config-decryptor/pkg/jasypt"
+	_ "github.com/grepplabs/spring-config-decryptor/pkg/kms"
+)
+
+// decryptorFlagNames are the flags selecting the decryptors, if none is set the decryptors of the config file are used.
+var decryptorFlagNames = map[string]bool{
+	"k": true, "keyring": true,
+	"kms": true, "kms-endpoint": true, "kms-region": true, "kms-key-id": true,
+	"jasypt": true, "jasypt-password-file": true, "jasypt-algorithm": true, "jasypt-iterations": true, "jasypt-prefix": true, "jasypt-suffix": true,
+}
+
+const configFlagUsage = "The repository configuration with the decryptors used when no key flag is set. If empty " + encryptor.ConfigFileName + " is looked up from the directory of the input upwards."
+
+// valueDecryptors are the schemes of the encrypted values selected by the flags of a command or by the config file.
+type valueDecryptors struct {
+	schemes []decryptor.Scheme
+	// rsa is the RSA decryptor of the key source and keyring, nil without key or with the config file decryptors
+	rsa *decryptor.ValueDecryptor
+	// key is the first private key of the key source, nil if it was not read
+	key []byte
+}
+
+// missingKeyDecryptor fails every {cipher} value when only other decryptors are configured.
+type missingKeyDecryptor struct {
+	err error
+}
+
+func (d missingKeyDecryptor) DecryptValueWithKey(string) ([]byte, string, error) {
+	return nil, "", d.err
+}
+
+// newValueDecryptors creates the decryptors of the config file if none of the decryptor flags is set and the file
+// configures decryptors, otherwise the decryptors of the flags.
+func newValueDecryptors(fs *flag.FlagSet, configFile, input, keyFile string, keyring []string, kmsFlags *kmsFlags, jasyptFlags *jasyptFlags) (*valueDecryptors, error) {
+	if input == "-" {
+		if err := checkStdinKey(keyFile); err != nil {
+			return nil, err
+		}
+	}
+	flagSet := false
+	fs.Visit(func(f *flag.Flag) {
+		flagSet = flagSet || decryptorFlagNames[f.Name]
+	})
+	if !flagSet {
+		configs, err := readDecryptorConfigs(configFile, input)
+		if err != nil {
+			return nil, err
+		}
+		if len(configs) != 0 {
+			schemes, err := decryptor.NewSchemes(configs)
+			if err != nil {
+				return nil, err
+			}
+			return &valueDecryptors{schemes: schemes}, nil
+		}
+	}
+	return newFlagDecryptors(keyFile, keyring, kmsFlags, jasyptFlags)
+}
+
+// checkStdinKey rejects a key source reading stdin, for commands whose input is stdin.
+func checkStdinKey(keyFile string) error {
+	if decryptor.IsStdinKeySource(keyFile) {
+		return fmt.Errorf("key source '%s' reads stdin, which is already the input, use another key source", keyFile)
+	}
+	return nil
+}
+
+// readDecryptorConfigs reads the decryptors of the config file or of the nearest config file of the input.
+func readDecryptorConfigs(configFile, input string) ([]decryptor.SchemeConfig, error) {
+	if configFile == "" {
+		dir := "."
+		if info, err := os.Stat(input); err == nil {
+			dir = input
+			if !info.IsDir() {
+				dir = filepath.Dir(input)
+			}
+		}
+		var err error
+		if configFile, err = encryptor.FindConfig(dir); err != nil || configFile == "" {
+			return nil, err
+		}
+	}
+	f, err := os.Open(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("config open file error: %v", err)
+	}
+	defer f.Close()
+	config, err := encryptor.LoadConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", configFile, err)
+	}
+	return config.Decryptors, nil
+}
+
+// newFlagDecryptors creates the RSA decryptor of the key source and keyring and combines it with the KMS decryptor,
+// the Jasypt decryptor is added for its marker. With -kms or -jasypt the RSA key is optional.
+func newFlagDecryptors(keyFile string, keyring []string, kmsFlags *kmsFlags, jasyptFlags *jasyptFlags) (*valueDecryptors, error) {
+	result := &valueDecryptors{}
+	kmsDecryptor, err := kmsFlags.decryptor()
+	if err != nil {
+		return nil, err
+	}
+	jasyptDecryptor, err := jasyptFlags.decryptor()
+	if err != nil {
+		return nil, err
+	}
+	var cipherDecryptor decryptor.CipherDecryptor
+	keys, err := readKeys(keyFile)
+	if err != nil {
+		optional := (kmsDecryptor != nil || jasyptDecryptor != nil) && keyFile == "" && len(keyring) == 0
+		if !optional || !errors.Is(err, errMissingKey) {
+			return nil, err
+		}
+		cipherDecryptor = kmsDecryptor
+		if kmsDecryptor == nil {
+			cipherDecryptor = missingKeyDecryptor{err: err}
+		}
+	} else {
+		keyringOptions, err := readKeyring(keys[1:], keyring)
+		if err != nil {
+			return nil, err
+		}
+		result.key = keys[0]
+		result.rsa, err = decryptor.NewValueDecryptor(keys[0], keyringOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("create decryptor error: %v", err)
+		}
+		cipherDecryptor = decryptor.NewFormatDecryptor(result.rsa, kmsDecryptor)
+	}
+	result.schemes = []decryptor.Scheme{decryptor.NewCipherScheme(cipherDecryptor)}
+	if jasyptDecryptor != nil {
+		result.schemes = append(result.schemes, decryptor.NewMarkerScheme(jasyptDecryptor.Prefix(), jasyptDecryptor.Suffix(), jasyptDecryptor))
+	}
+	return result, nil
+}
+
+// encryptionKey returns the private key which encrypts new values. It is the key already read from the key source,
+// so a stdin:// or fd:// source is not read twice, otherwise the key of the environment variables.
+func (d *valueDecryptors) encryptionKey() ([]byte, error) {
+	if d.key != nil {
+		return d.key, nil
+	}
+	return readKey("")
+}
+
+// writeKeyUsage prints the number of values each key of the keyring decrypted, nothing without keyring.
+func (d *valueDecryptors) writeKeyUsage(keys map[string]int) {
+	if d.rsa != nil && len(d.rsa.Fingerprints()) > 1 {
+		writeKeyUsage(os.Stderr, d.rsa.Fingerprints(), keys)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/configdiff"
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+)
+
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	var (
+		keyFile = fs.String("k", "", keyFlagUsage)
+		reveal  = fs.Bool("reveal", false, "Print the plain text of the encrypted values instead of their hashes.")
+		salt    = fs.String("salt", "", "The salt of the hashes of the encrypted values. If empty a random salt is used, so the hashes are comparable only within one run.")
+		format  = fs.String("format", "text", "The output format: text or json.")
+		output  = fs.String("o", "-", `The file to write the diff to. Use '-' for stdout.`)
+		config  = fs.String("config", "", configFlagUsage)
+		keyring stringsFlag
+	)
+	fs.Var(&keyring, "keyring", keyringFlagUsage)
+	kmsOptions := addKMSFlags(fs)
+	jasyptOptions := addJasyptFlags(fs)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s diff [flags] <old> <new>\n", os.Args[0])
+		_, _ = fmt.Fprintf(fs.Output(), "The files can be given as git:<rev>:<path> e.g. git:HEAD~1:config/application.yml\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" {
+		exitOnError("unsupported format '%s', expected text or json", *format)
+	}
+
+	oldName, newName := fs.Arg(0), fs.Arg(1)
+	// a git:<rev>:<path> reference looks the config file up from the current directory
+	valueDecryptors, err := newValueDecryptors(fs, *config, newName, *keyFile, keyring, kmsOptions, jasyptOptions)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	valueDecryptor := decryptor.NewSchemeDecryptor(valueDecryptors.schemes)
+	oldValues, err := loadDiffSide(oldName, valueDecryptor)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	newValues, err := loadDiffSide(newName, valueDecryptor)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+
+	formatter := configdiff.Formatter{Reveal: *reveal, Salt: []byte(*salt)}
+	if *salt == "" {
+		formatter.Salt = make([]byte, 16)
+		if _, err = rand.Read(formatter.Salt); err != nil {
+			exitOnError("salt generation error: %v", err)
+		}
+	}
+	w, closeOutput, err := openOutput(*output, true)
+	if err != nil {
+		exitOnError("output open file error: %v", err)
+	}
+	changes := configdiff.Compare(oldValues, newValues)
+	if *format == "json" {
+		err = formatter.WriteJSON(w, oldName, newName, changes)
+	} else {
+		err = formatter.WriteText(w, oldName, newName, changes)
+	}
+	if err != nil {
+		exitOnError("diff write error: %v", err)
+	}
+	closeOutput()
+	if len(changes) != 0 {
+		os.Exit(1)
+	}
+}
+
+// loadDiffSide reads and decrypts a file or a git:<rev>:<path> reference.
+func loadDiffSide(name string, valueDecryptor configdiff.TextDecryptor) (map[string]configdiff.Value, error) {
+	fileName := name
+	var (
+		content []byte
+		err     error
+	)
+	if ref := strings.TrimPrefix(name, "git:"); ref != name {
+		i := strings.Index(ref, ":")
+		if i == -1 {
+			return nil, fmt.Errorf("invalid git reference '%s', expected git:<rev>:<path>", name)
+		}
+		fileName = ref[i+1:]
+		// a revision like --output=x would be an option of git show
+		if strings.HasPrefix(ref, "-") {
+			return nil, fmt.Errorf("invalid git reference '%s', the revision must not start with '-'", name)
+		}
+		var stderr bytes.Buffer
+		cmd := exec.Command("git", "show", ref)
+		cmd.Stderr = &stderr
+		if content, err = cmd.Output(); err != nil {
+			return nil, fmt.Errorf("git show %s error: %v %s", ref, err, strings.TrimSpace(stderr.String()))
+		}
+	} else if content, err = ioutil.ReadFile(name); err != nil {
+		return nil, fmt.Errorf("input read file error: %v", err)
+	}
+	return configdiff.Load(fileName, content, valueDecryptor)
+}
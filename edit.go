@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/encryptor"
+)
+
+// privateTempDirs are tried in order for the decrypted file, tmpfs is preferred to keep the plain text off the disk.
+var privateTempDirs = []string{"/dev/shm", os.Getenv("XDG_RUNTIME_DIR"), ""}
+
+func runEdit(args []string) {
+	fs := flag.NewFlagSet("edit", flag.ExitOnError)
+	var (
+		keyFile  = fs.String("k", "", keyFlagUsage)
+		tag      = fs.String("tag", encryptor.DefaultTag, "The prefix marking the plain text values to encrypt.")
+		keyAlias = fs.String("key-alias", "", "The {key:alias} prefix of the newly encrypted values.")
+		config   = fs.String("config", "", configFlagUsage)
+		keyring  stringsFlag
+	)
+	fs.Var(&keyring, "keyring", keyringFlagUsage)
+	kmsOptions := addKMSFlags(fs)
+	jasyptOptions := addJasyptFlags(fs)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s edit [flags] <file>\n", os.Args[0])
+		_, _ = fmt.Fprintf(fs.Output(), "The file is decrypted into a private temporary file and opened with $VISUAL or $EDITOR.\n")
+		_, _ = fmt.Fprintf(fs.Output(), "The values prefixed with -tag are encrypted again with the RSA key, unchanged values keep their cipher text.\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	valueDecryptors, err := newValueDecryptors(fs, *config, fs.Arg(0), *keyFile, keyring, kmsOptions, jasyptOptions)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	key, err := valueDecryptors.encryptionKey()
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	valueEncryptor, err := encryptor.NewValueEncryptor(key, encryptor.WithKeyAlias(*keyAlias))
+	if err != nil {
+		exitOnError("create encryptor error: %v", err)
+	}
+	editor := encryptor.NewEditor(decryptor.NewSchemeDecryptor(valueDecryptors.schemes), valueEncryptor, encryptor.WithTag(*tag))
+	if err = edit(editor, fs.Arg(0)); err != nil {
+		exitOnError("edit error: %v", err)
+	}
+}
+
+func edit(editor *encryptor.Editor, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	original, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	plainText, err := editor.Decrypt(path, original)
+	if err != nil {
+		return err
+	}
+
+	dir, err := privateTempDir()
+	if err != nil {
+		return err
+	}
+	defer shredDir(dir)
+	// shred the plain text also when terminated, interrupts are left to the editor
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			if sig != os.Interrupt {
+				shredDir(dir)
+				os.Exit(1)
+			}
+		}
+	}()
+
+	tempFile := filepath.Join(dir, filepath.Base(path))
+	if err = ioutil.WriteFile(tempFile, plainText, 0600); err != nil {
+		return err
+	}
+	if err = runEditor(tempFile); err != nil {
+		return err
+	}
+	edited, err := ioutil.ReadFile(tempFile)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(edited, plainText) {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: no changes\n", path)
+		return nil
+	}
+	encrypted, count, err := editor.Encrypt(path, original, edited)
+	if err != nil {
+		return fmt.Errorf("%v, the changes are discarded", err)
+	}
+	if err = ioutil.WriteFile(path, encrypted, info.Mode()); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "%s: encrypted %d values\n", path, count)
+	return nil
+}
+
+func runEditor(file string) error {
+	command := os.Getenv("VISUAL")
+	if command == "" {
+		command = os.Getenv("EDITOR")
+	}
+	if command == "" {
+		command = "vi"
+	}
+	// the editor may contain arguments e.g. "code --wait"
+	args := append(strings.Fields(command), file)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s error: %v", args[0], err)
+	}
+	return nil
+}
+
+// privateTempDir creates a directory accessible only by the current user, editors keep their swap files next to the edited file.
+func privateTempDir() (string, error) {
+	var err error
+	for _, parent := range privateTempDirs {
+		if parent != "" {
+			if info, statErr := os.Stat(parent); statErr != nil || !info.IsDir() {
+				continue
+			}
+		}
+		var dir string
+		if dir, err = ioutil.TempDir(parent, "spring-config-edit-"); err == nil {
+			return dir, nil
+		}
+	}
+	return "", err
+}
+
+// shredDir overwrites the files of the directory with zeros before removing it.
+func shredDir(dir string) {
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return nil
+		}
+		_, _ = f.Write(make([]byte, info.Size()))
+		_ = f.Sync()
+		_ = f.Close()
+		return nil
+	})
+	if err := os.RemoveAll(dir); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "warning: temporary directory %s cannot be removed: %v\n", dir, err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/encryptor"
+)
+
+func runEncryptFile(args []string) {
+	fs := flag.NewFlagSet("encrypt-file", flag.ExitOnError)
+	var (
+		paths          stringsFlag
+		keyFile        = fs.String("k", "", "The RSA public or private key source: a file or a key source URI like env://NAME. If empty the key is read from environment variable "+defaultEnvEncryptKey+" / "+defaultEnvEncryptKeyBase64)
+		encryptedRegex = fs.String("encrypted-regex", "", "The regular expression matching the property paths to encrypt.")
+		configFile     = fs.String("config", "", "The file with the encryption rules. If empty "+encryptor.ConfigFileName+" is looked up from the directory of the file upwards.")
+		keyAlias       = fs.String("key-alias", "", "The {key:alias} prefix of the encrypted values.")
+		salt           = fs.String("salt", decryptor.DefaultSalt, "The hex encoded salt of the encrypted values.")
+		algorithm      = fs.String("algorithm", string(decryptor.RsaAlgorithmDefault), "The RSA algorithm of the encrypted values: DEFAULT or OAEP.")
+		strong         = fs.Bool("strong", false, "Use AES/GCM for the encrypted values.")
+		inPlace        = fs.Bool("i", false, "Encrypt the files in place.")
+		output         = fs.String("o", "-", `The file to write the result to. Use '-' for stdout.`)
+	)
+	fs.Var(&paths, "path", "The property path or path glob to encrypt, can be repeated.")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s encrypt-file [flags] <file>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 || (!*inPlace && fs.NArg() != 1) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	rsaAlgorithm, err := decryptor.ParseRsaAlgorithm(*algorithm)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	key, err := readKey(*keyFile)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	valueEncryptor, err := encryptor.NewValueEncryptor(key, encryptor.WithKeyAlias(*keyAlias), encryptor.WithSalt(*salt), encryptor.WithAlgorithm(rsaAlgorithm), encryptor.WithStrong(*strong))
+	if err != nil {
+		exitOnError("create encryptor error: %v", err)
+	}
+
+	for _, fileName := range fs.Args() {
+		rule, err := encryptionRule(fileName, *configFile, *encryptedRegex, paths)
+		if err != nil {
+			exitOnError("%v", err)
+		}
+		match, err := rule.Matcher()
+		if err != nil {
+			exitOnError("%v", err)
+		}
+		content, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			exitOnError("input read file error: %v", err)
+		}
+		encrypted, count, err := encryptor.NewFileEncryptor(valueEncryptor, match).Encrypt(fileName, content)
+		if err != nil {
+			exitOnError("encrypt error: %v", err)
+		}
+		if *inPlace {
+			if count == 0 {
+				continue
+			}
+			info, err := os.Stat(fileName)
+			if err != nil {
+				exitOnError("%v", err)
+			}
+			if err = ioutil.WriteFile(fileName, encrypted, info.Mode()); err != nil {
+				exitOnError("output write file error: %v", err)
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "%s: encrypted %d values\n", fileName, count)
+			continue
+		}
+		w, closeOutput, err := openOutput(*output, true)
+		if err != nil {
+			exitOnError("output open file error: %v", err)
+		}
+		if _, err = w.Write(encrypted); err != nil {
+			exitOnError("output write error: %v", err)
+		}
+		closeOutput()
+	}
+}
+
+// encryptionRule returns the rule given by the flags or the rule of the config file matching the file.
+func encryptionRule(fileName, configFile, encryptedRegex string, paths []string) (*encryptor.Rule, error) {
+	if encryptedRegex != "" || len(paths) != 0 {
+		return &encryptor.Rule{EncryptedRegex: encryptedRegex, Paths: paths}, nil
+	}
+	if configFile == "" {
+		var err error
+		if configFile, err = encryptor.FindConfig(filepath.Dir(fileName)); err != nil {
+			return nil, err
+		}
+		if configFile == "" {
+			return nil, fmt.Errorf("%s: use -encrypted-regex, -path or provide %s", fileName, encryptor.ConfigFileName)
+		}
+	}
+	f, err := os.Open(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("config open file error: %v", err)
+	}
+	defer f.Close()
+	config, err := encryptor.LoadConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", configFile, err)
+	}
+	absConfig, err := filepath.Abs(configFile)
+	if err != nil {
+		return nil, err
+	}
+	absFile, err := filepath.Abs(fileName)
+	if err != nil {
+		return nil, err
+	}
+	relativePath, err := filepath.Rel(filepath.Dir(absConfig), absFile)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := config.Rule(relativePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", configFile, err)
+	}
+	if rule == nil {
+		return nil, fmt.Errorf("%s: no encryption rule matches %s", configFile, relativePath)
+	}
+	return rule, nil
+}
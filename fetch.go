@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/configserver"
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+)
+
+func runFetch(args []string) {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	var (
+		uri         = fs.String("uri", envOrDefault("SPRING_CLOUD_CONFIG_URI", "http://localhost:8888"), "The Config Server URI.")
+		application = fs.String("application", "", "The application name.")
+		profile     = fs.String("profile", "default", "The comma separated list of profiles.")
+		label       = fs.String("label", "", "The label (branch, tag or commit). If empty the server default is used.")
+		username    = fs.String("username", os.Getenv("SPRING_CLOUD_CONFIG_USERNAME"), "The basic auth username.")
+		password    = fs.String("password", os.Getenv("SPRING_CLOUD_CONFIG_PASSWORD"), "The basic auth password.")
+		token       = fs.String("token", os.Getenv("SPRING_CLOUD_CONFIG_TOKEN"), "The bearer token. Takes precedence over basic auth.")
+		timeout     = fs.Duration("timeout", 10*time.Second, "The timeout of a single request.")
+		retries     = fs.Int("retries", 3, "The number of retries on connection errors and 5xx responses.")
+		retryWait   = fs.Duration("retry-wait", time.Second, "The wait time between retries.")
+		format      = fs.String("format", string(properties.FormatYAML), "The output format: yaml, properties, json or env.")
+		output      = fs.String("o", "-", `The file to write the result to. Use '-' for stdout.`)
+		keyFile     = fs.String("k", "", keyFlagUsage)
+		config      = fs.String("config", "", configFlagUsage)
+		keyring     stringsFlag
+	)
+	fs.Var(&keyring, "keyring", keyringFlagUsage)
+	kmsOptions := addKMSFlags(fs)
+	jasyptOptions := addJasyptFlags(fs)
+	resolveOptions := addResolveFlags(fs)
+	_ = fs.Parse(args)
+
+	outputFormat, err := properties.ParseFormat(*format)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	// the config file is looked up from the current directory
+	valueDecryptors, err := newValueDecryptors(fs, *config, ".", *keyFile, keyring, kmsOptions, jasyptOptions)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	options := []configserver.ClientOption{
+		configserver.WithToken(*token),
+		configserver.WithTimeout(*timeout),
+		configserver.WithRetries(*retries, *retryWait),
+	}
+	if *username != "" {
+		options = append(options, configserver.WithBasicAuth(*username, *password))
+	}
+	client, err := configserver.NewClient(*uri, options...)
+	if err != nil {
+		exitOnError("create config client error: %v", err)
+	}
+	env, err := client.Fetch(context.Background(), *application, *profile, *label)
+	if err != nil {
+		exitOnError("fetch error: %v", err)
+	}
+	if err = env.Decrypt(decryptor.NewSchemeDecryptor(valueDecryptors.schemes)); err != nil {
+		exitOnError("decrypt error: %v", err)
+	}
+
+	props, err := resolveOptions.resolve(env.Properties())
+	if err != nil {
+		exitOnError("resolve error: %v", err)
+	}
+
+	out, closeOutput, err := openOutput(*output, true)
+	if err != nil {
+		exitOnError("output open file error: %v", err)
+	}
+	defer closeOutput()
+	if err = properties.Write(out, props, outputFormat); err != nil {
+		exitOnError("output write error: %v", err)
+	}
+}
+
+func envOrDefault(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
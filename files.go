@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// walkFiles calls fn for each regular text file in the given files and directories.
+// Hidden directories like .git are skipped.
+func walkFiles(paths []string, fn func(path string, content []byte) error) error {
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != root && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if path != root && bytes.IndexByte(content, 0) != -1 {
+				// binary file
+				return nil
+			}
+			return fn(path, content)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+const (
+	toolName           = "spring-config-decryptor"
+	toolInformationURI = "https://github.com/grepplabs/spring-config-decryptor"
+	sarifSchema        = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion       = "2.1.0"
+)
+
+type rule struct {
+	ID          string
+	Description string
+}
+
+type finding struct {
+	RuleID   string `json:"ruleId"`
+	Level    string `json:"level"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Path     string `json:"property,omitempty"`
+	KeyAlias string `json:"keyAlias,omitempty"`
+	Message  string `json:"message"`
+}
+
+type findingsReport struct {
+	Files    int       `json:"files"`
+	Values   int       `json:"values,omitempty"`
+	Findings []finding `json:"findings"`
+	// Keys counts the decrypted values by key fingerprint
+	Keys map[string]int `json:"keys,omitempty"`
+}
+
+func validateReportFormat(format string) error {
+	switch format {
+	case "text", "json", "sarif":
+		return nil
+	}
+	return fmt.Errorf("unsupported report format '%s', expected one of text, json or sarif", format)
+}
+
+func writeFindings(w io.Writer, format string, rules []rule, report findingsReport) error {
+	switch format {
+	case "text":
+		for _, f := range report.Findings {
+			if _, err := fmt.Fprintf(w, "%s:%d:%d: %s: %s\n", f.File, f.Line, f.Column, f.Level, f.Message); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		if report.Findings == nil {
+			report.Findings = []finding{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "sarif":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(newSarifLog(rules, report.Findings))
+	}
+	return validateReportFormat(format)
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+func newSarifLog(rules []rule, findings []finding) sarifLog {
+	driver := sarifDriver{Name: toolName, InformationURI: toolInformationURI, Rules: []sarifRule{}}
+	for _, r := range rules {
+		driver.Rules = append(driver.Rules, sarifRule{ID: r.ID, ShortDescription: sarifMessage{Text: r.Description}})
+	}
+	results := []sarifResult{}
+	for _, f := range findings {
+		results = append(results, sarifResult{
+			RuleID:  f.RuleID,
+			Level:   f.Level,
+			Message: sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(f.File)},
+				Region:           sarifRegion{StartLine: f.Line, StartColumn: f.Column},
+			}}},
+		})
+	}
+	return sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}}}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/rsa"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/encryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/keys"
+)
+
+func runFingerprint(args []string) {
+	fs := flag.NewFlagSet("fingerprint", flag.ExitOnError)
+	var (
+		keyFile        = fs.String("k", "", keyFlagUsage)
+		passphraseFile = fs.String("passphrase-file", "", "The file with the passphrase of an encrypted private key.")
+	)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s fingerprint [flags] [key-file...]\n", os.Args[0])
+		_, _ = fmt.Fprintf(fs.Output(), "Prints the SHA-256 fingerprint of the public key of each public or private key file, or of the -k key if no file is given.\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		key, err := readPrivateKey(*keyFile, *passphraseFile)
+		if err != nil {
+			exitOnError("%v", err)
+		}
+		printFingerprint(&key.PublicKey, "")
+		return
+	}
+	var passphrase []byte
+	if *passphraseFile != "" {
+		var err error
+		if passphrase, err = readSecretFile(*passphraseFile); err != nil {
+			exitOnError("passphrase %v", err)
+		}
+	}
+	for _, name := range fs.Args() {
+		publicKey, err := readPublicKey(name, passphrase)
+		if err != nil {
+			exitOnError("%v", err)
+		}
+		printFingerprint(publicKey, name)
+	}
+}
+
+// readPublicKey reads the public key of a public or private key file. A PEM private key reports its own error,
+// e.g. of a wrong passphrase, other files which are neither key report both errors.
+func readPublicKey(name string, passphrase []byte) (*rsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("key file reading error: %v", err)
+	}
+	if block, _ := pem.Decode(data); block != nil && strings.HasSuffix(block.Type, "PRIVATE KEY") {
+		privateKey, err := keys.ParsePrivateKey(data, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		return &privateKey.PublicKey, nil
+	}
+	publicKey, publicErr := encryptor.ParsePublicKey(data)
+	if publicErr == nil {
+		return publicKey, nil
+	}
+	privateKey, privateErr := keys.ParsePrivateKey(data, passphrase)
+	if privateErr != nil {
+		return nil, fmt.Errorf("%s: public key error: %v; private key error: %v", name, publicErr, privateErr)
+	}
+	return &privateKey.PublicKey, nil
+}
+
+func printFingerprint(key *rsa.PublicKey, name string) {
+	fingerprint, err := decryptor.Fingerprint(key)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	if name == "" {
+		fmt.Println(fingerprint)
+	} else {
+		fmt.Printf("%s  %s\n", fingerprint, name)
+	}
+}
+
+// readPrivateKey reads the private key like readKey and decrypts it if it is encrypted.
+func readPrivateKey(keyFile string, passphraseFile string) (*rsa.PrivateKey, error) {
+	key, err := readKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	var passphrase []byte
+	if passphraseFile != "" {
+		if passphrase, err = readSecretFile(passphraseFile); err != nil {
+			return nil, fmt.Errorf("passphrase %v", err)
+		}
+	}
+	privateKey, err := keys.ParsePrivateKey(key, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("private key error: %v", err)
+	}
+	return privateKey, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/keys"
+)
+
+func TestReadPublicKey(t *testing.T) {
+	key, err := keys.Generate(keys.MinBits)
+	if err != nil {
+		t.Fatalf("generate key error: %v", err)
+	}
+	encrypted, err := keys.EncodePrivateKey(key, keys.FormatPKCS8, []byte("secret"))
+	if err != nil {
+		t.Fatalf("encode private key error: %v", err)
+	}
+	public, err := keys.EncodePublicKey(&key.PublicKey, keys.FormatPKIX)
+	if err != nil {
+		t.Fatalf("encode public key error: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "fingerprint")
+	if err != nil {
+		t.Fatalf("temp dir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string][]byte{"private.pem": encrypted, "public.pem": public, "garbage.pem": []byte("garbage")}
+	for name, data := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatalf("write file error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		file       string
+		passphrase string
+		err        string
+	}{
+		{file: "private.pem", passphrase: "secret"},
+		{file: "public.pem"},
+		{file: "private.pem", err: keys.ErrPassphraseRequired.Error()},
+		// the wrong passphrase is reported instead of a public key error
+		{file: "private.pem", passphrase: "wrong", err: "private.pem: "},
+		{file: "garbage.pem", err: "public key error: "},
+	}
+	for _, tc := range tests {
+		publicKey, err := readPublicKey(filepath.Join(dir, tc.file), []byte(tc.passphrase))
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) || (tc.file == "private.pem" && strings.Contains(err.Error(), "public key")) {
+				t.Errorf("%s %q: unexpected error %v", tc.file, tc.passphrase, err)
+			}
+			continue
+		}
+		if err != nil || publicKey.N.Cmp(key.PublicKey.N) != 0 {
+			t.Errorf("%s: unexpected public key %v", tc.file, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/lint"
+)
+
+func runFix(args []string) {
+	fs := flag.NewFlagSet("fix", flag.ExitOnError)
+	check := fs.Bool("check", false, "Only print the diff of the required changes and exit with 1 if there are any.")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s fix [flags] <file or directory>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	changed := 0
+	err := walkFiles(fs.Args(), func(path string, content []byte) error {
+		if !lint.Supported(path) {
+			return nil
+		}
+		fixed, changes := lint.Fix(path, content)
+		if len(changes) == 0 {
+			return nil
+		}
+		changed++
+		if *check {
+			return lint.WriteDiff(os.Stdout, path, changes)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(path, fixed, info.Mode()); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "fixed %d values in %s\n", len(changes), path)
+		return nil
+	})
+	if err != nil {
+		exitOnError("fix error: %v", err)
+	}
+	if *check && changed != 0 {
+		os.Exit(1)
+	}
+}
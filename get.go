@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+)
+
+func runGet(args []string) {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	var (
+		format      = fs.String("format", "raw", "The output format: raw or json.")
+		inputFormat = fs.String("input-format", "", "The input format: yaml, properties or json. If empty it is derived from the file extension.")
+		noNewline   = fs.Bool("n", false, "Do not print the trailing newline of the raw value.")
+		keyFile     = fs.String("k", "", keyFlagUsage)
+		config      = fs.String("config", "", configFlagUsage)
+		keyring     stringsFlag
+	)
+	fs.Var(&keyring, "keyring", keyringFlagUsage)
+	kmsOptions := addKMSFlags(fs)
+	jasyptOptions := addJasyptFlags(fs)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s get [flags] <property path> <file or '-' for stdin>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "raw" && *format != "json" {
+		exitOnError("unsupported format '%s', expected one of [raw json]", *format)
+	}
+	path, fileName := fs.Arg(0), fs.Arg(1)
+	propsFormat := properties.FormatOf(fileName)
+	if *inputFormat != "" {
+		var err error
+		if propsFormat, err = properties.ParseFormat(*inputFormat); err != nil {
+			exitOnError("%v", err)
+		}
+	}
+
+	in, closeInput, err := openInput(fileName)
+	if err != nil {
+		exitOnError("input open file error: %v", err)
+	}
+	defer closeInput()
+	props, err := properties.Read(in, propsFormat)
+	if err != nil {
+		exitOnError("%s: %v", fileName, err)
+	}
+	key, value, ok := props.Lookup(path)
+	if !ok {
+		for _, k := range props.Keys() {
+			if strings.HasPrefix(k, path+".") || strings.HasPrefix(k, path+"[") {
+				exitOnError("property '%s' in %s is not a single value", path, fileName)
+			}
+		}
+		exitOnError("property '%s' not found in %s", path, fileName)
+	}
+
+	// only the value is decrypted, plain values do not need a key
+	if s, ok := value.(string); ok {
+		valueDecryptors, err := newValueDecryptors(fs, *config, fileName, *keyFile, keyring, kmsOptions, jasyptOptions)
+		if err != nil {
+			if !errors.Is(err, errMissingKey) || decryptor.IsCipherValue(s) {
+				exitOnError("%v", err)
+			}
+		} else if value, err = decryptor.NewSchemeDecryptor(valueDecryptors.schemes).DecryptValue(s); err != nil {
+			exitOnError("%v", &decryptor.DecryptError{File: fileName, Path: key, Err: err})
+		}
+	}
+
+	var out []byte
+	if *format == "json" {
+		if out, err = json.Marshal(value); err != nil {
+			exitOnError("json encoding error: %v", err)
+		}
+	} else {
+		out = []byte(properties.ToString(value))
+	}
+	if !*noNewline {
+		out = append(out, '\n')
+	}
+	_, _ = os.Stdout.Write(out)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/gitrepo"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+)
+
+// formatEnvironment writes the environment as the JSON response of the Config Server.
+const formatEnvironment = "environment"
+
+func runGitEnv(args []string) {
+	fs := flag.NewFlagSet("git-env", flag.ExitOnError)
+	var (
+		application = fs.String("application", "application", "The application name.")
+		profile     = fs.String("profile", "default", "The comma separated list of profiles.")
+		label       = fs.String("label", "", "The label (branch, tag or commit). If empty "+gitrepo.DefaultLabel+" or master is used.")
+		searchPaths = fs.String("search-paths", "", "The comma separated search-paths of the Config Server with {application}, {profile} and {label} placeholders and * wildcards.")
+		format      = fs.String("format", string(properties.FormatYAML), "The output format: yaml, properties, json, env or "+formatEnvironment+" for the JSON response of the Config Server.")
+		output      = fs.String("o", "-", `The file to write the result to. Use '-' for stdout.`)
+		keyFile     = fs.String("k", "", keyFlagUsage)
+		config      = fs.String("config", "", configFlagUsage)
+		keyring     stringsFlag
+	)
+	fs.Var(&keyring, "keyring", keyringFlagUsage)
+	kmsOptions := addKMSFlags(fs)
+	jasyptOptions := addJasyptFlags(fs)
+	resolveOptions := addResolveFlags(fs)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s git-env [flags] [git repository]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	var outputFormat properties.Format
+	if *format != formatEnvironment {
+		var err error
+		if outputFormat, err = properties.ParseFormat(*format); err != nil {
+			exitOnError("%v", err)
+		}
+	} else if *resolveOptions.enabled || *resolveOptions.strict {
+		exitOnError("placeholders cannot be resolved with -format %s", formatEnvironment)
+	}
+
+	valueDecryptors, err := newValueDecryptors(fs, *config, dir, *keyFile, keyring, kmsOptions, jasyptOptions)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	repository, err := gitrepo.NewRepository(dir, gitrepo.WithSearchPaths(strings.Split(*searchPaths, ",")...))
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	env, err := repository.Environment(*application, *profile, *label)
+	if err != nil {
+		exitOnError("git-env error: %v", err)
+	}
+	if err = env.Decrypt(decryptor.NewSchemeDecryptor(valueDecryptors.schemes)); err != nil {
+		exitOnError("decrypt error: %v", err)
+	}
+	props, err := resolveOptions.resolve(env.Properties())
+	if err != nil {
+		exitOnError("resolve error: %v", err)
+	}
+
+	out, closeOutput, err := openOutput(*output, true)
+	if err != nil {
+		exitOnError("output open file error: %v", err)
+	}
+	defer closeOutput()
+	if *format == formatEnvironment {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(env)
+	} else {
+		err = properties.Write(out, props, outputFormat)
+	}
+	if err != nil {
+		exitOnError("output write error: %v", err)
+	}
+}
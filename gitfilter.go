@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/encryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/gitfilter"
+)
+
+func runGitFilter(args []string) {
+	fs := flag.NewFlagSet("git-filter", flag.ExitOnError)
+	var (
+		keyFile  = fs.String("k", "", keyFlagUsage)
+		tag      = fs.String("tag", encryptor.DefaultTag, "The prefix marking the plain text values to encrypt.")
+		keyAlias = fs.String("key-alias", "", "The {key:alias} prefix of the newly encrypted values.")
+		config   = fs.String("config", "", configFlagUsage)
+		keyring  stringsFlag
+	)
+	fs.Var(&keyring, "keyring", keyringFlagUsage)
+	kmsOptions := addKMSFlags(fs)
+	jasyptOptions := addJasyptFlags(fs)
+	fs.Usage = func() {
+		out := fs.Output()
+		_, _ = fmt.Fprintf(out, "Usage: %s git-filter smudge|clean|textconv [flags] <path>\n", os.Args[0])
+		_, _ = fmt.Fprintf(out, "  smudge    decrypt the blob read from stdin for the working tree\n")
+		_, _ = fmt.Fprintf(out, "  clean     encrypt the working tree file read from stdin, unchanged values keep their cipher text\n")
+		_, _ = fmt.Fprintf(out, "  textconv  print the file decrypted for git diff\n")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	mode := args[0]
+	_ = fs.Parse(args[1:])
+	if fs.NArg() != 1 || (mode != "smudge" && mode != "clean" && mode != "textconv") {
+		fs.Usage()
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+	// smudge and clean read the blob from stdin
+	if mode != "textconv" {
+		if err := checkStdinKey(*keyFile); err != nil {
+			exitOnError("%v", err)
+		}
+	}
+	newFilter := func() (*gitfilter.Filter, error) {
+		valueDecryptors, err := newValueDecryptors(fs, *config, path, *keyFile, keyring, kmsOptions, jasyptOptions)
+		if err != nil {
+			return nil, err
+		}
+		// only clean encrypts, so smudge and textconv do not need the RSA key
+		return newGitFilter(valueDecryptors, mode == "clean", *tag, *keyAlias)
+	}
+
+	if mode == "smudge" {
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			exitOnError("input read error: %v", err)
+		}
+		// without the key the checkout must not fail, the file stays encrypted
+		filter, err := newFilter()
+		if err == nil {
+			var decrypted []byte
+			if decrypted, err = filter.Smudge(path, content); err == nil {
+				content = decrypted
+			}
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "warning: %s is left encrypted: %v\n", path, err)
+		}
+		if _, err = os.Stdout.Write(content); err != nil {
+			exitOnError("output write error: %v", err)
+		}
+		return
+	}
+
+	filter, err := newFilter()
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	switch mode {
+	case "clean":
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			exitOnError("input read error: %v", err)
+		}
+		encrypted, err := filter.Clean(path, content)
+		if err != nil {
+			exitOnError("clean error: %v", err)
+		}
+		if _, err = os.Stdout.Write(encrypted); err != nil {
+			exitOnError("output write error: %v", err)
+		}
+	case "textconv":
+		input, closeInput, err := openInput(path)
+		if err != nil {
+			exitOnError("input open file error: %v", err)
+		}
+		defer closeInput()
+		if err = filter.Textconv(os.Stdout, input); err != nil {
+			exitOnError("textconv error: %v", err)
+		}
+	}
+}
+
+// newGitFilter creates the filter decrypting the values of the schemes, with encrypt the values are encrypted with the RSA key.
+func newGitFilter(valueDecryptors *valueDecryptors, encrypt bool, tag, keyAlias string) (*gitfilter.Filter, error) {
+	var valueEncryptor *encryptor.ValueEncryptor
+	if encrypt {
+		key, err := valueDecryptors.encryptionKey()
+		if err != nil {
+			return nil, err
+		}
+		if valueEncryptor, err = encryptor.NewValueEncryptor(key, encryptor.WithKeyAlias(keyAlias)); err != nil {
+			return nil, fmt.Errorf("create encryptor error: %v", err)
+		}
+	}
+	editor := encryptor.NewEditor(decryptor.NewSchemeDecryptor(valueDecryptors.schemes), valueEncryptor, encryptor.WithTag(tag))
+	return gitfilter.NewFilter(editor, valueDecryptors.schemes), nil
+}
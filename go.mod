@@ -5,4 +5,5 @@ go 1.14
 require (
 	github.com/pkg/errors v0.9.1
 	golang.org/x/crypto v0.0.0-20201002170205-7f63de1d35b0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+)
+
+func runInspect(args []string) {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	var (
+		keyFile   = fs.String("k", "", "The RSA private key source to test the decryption stages with: a file or a key source URI like env://NAME. If empty the key is read from environment variable "+defaultEnvEncryptKey+" / "+defaultEnvEncryptKeyBase64+" if set.")
+		salt      = fs.String("salt", decryptor.DefaultSalt, "The hex encoded salt of the value.")
+		algorithm = fs.String("algorithm", string(decryptor.RsaAlgorithmDefault), "The RSA algorithm of the value: DEFAULT or OAEP.")
+		strong    = fs.Bool("strong", false, "The value uses AES/GCM.")
+		format    = fs.String("format", "text", "The output format: text or json.")
+	)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s inspect [flags] '{cipher}...'\n", os.Args[0])
+		_, _ = fmt.Fprintf(fs.Output(), "Use '-' to read the value from stdin.\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" {
+		exitOnError("unsupported format '%s', expected text or json", *format)
+	}
+	value := fs.Arg(0)
+	if value == "-" {
+		if err := checkStdinKey(*keyFile); err != nil {
+			exitOnError("%v", err)
+		}
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			exitOnError("input read error: %v", err)
+		}
+		value = string(b)
+	}
+	value = strings.Trim(strings.TrimSpace(value), `'"`)
+
+	envelope := decryptor.Inspect(value)
+	if _, envErr := readKeys(""); *keyFile != "" || envErr == nil {
+		rsaAlgorithm, err := decryptor.ParseRsaAlgorithm(*algorithm)
+		if err != nil {
+			exitOnError("%v", err)
+		}
+		key, err := readKey(*keyFile)
+		if err != nil {
+			exitOnError("%v", err)
+		}
+		valueDecryptor, err := decryptor.NewValueDecryptor(key, decryptor.WithSalt(*salt), decryptor.WithAlgorithm(rsaAlgorithm), decryptor.WithStrong(*strong))
+		if err != nil {
+			exitOnError("create decryptor error: %v", err)
+		}
+		envelope = valueDecryptor.InspectValue(value)
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(envelope); err != nil {
+			exitOnError("json encoding error: %v", err)
+		}
+	} else {
+		writeEnvelope(os.Stdout, envelope)
+	}
+	if envelope.Error != "" {
+		os.Exit(1)
+	}
+	for _, stage := range envelope.Stages {
+		if !stage.OK {
+			os.Exit(1)
+		}
+	}
+}
+
+func writeEnvelope(w io.Writer, envelope *decryptor.Envelope) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+	if len(envelope.Prefixes) != 0 {
+		names := make([]string, 0, len(envelope.Prefixes))
+		for name := range envelope.Prefixes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			_, _ = fmt.Fprintf(tw, "prefix {%s:}:\t%s\n", name, envelope.Prefixes[name])
+		}
+	}
+	_, _ = fmt.Fprintf(tw, "length:\t%d bytes\n", envelope.Length)
+	if envelope.Length >= 2 {
+		sizes := "no common RSA key size"
+		if envelope.SessionKeyBits != 0 {
+			sizes = fmt.Sprintf("RSA %d", envelope.SessionKeyBits)
+		}
+		_, _ = fmt.Fprintf(tw, "session key length:\t%d bytes (%s)\n", envelope.SessionKeyLength, sizes)
+	}
+	if envelope.MatchesKey != nil {
+		_, _ = fmt.Fprintf(tw, "matches key:\t%t (RSA %d)\n", *envelope.MatchesKey, envelope.KeyBits)
+	}
+	if envelope.IV != "" {
+		_, _ = fmt.Fprintf(tw, "iv:\t%s\n", envelope.IV)
+	}
+	if envelope.Error == "" {
+		_, _ = fmt.Fprintf(tw, "payload length:\t%d bytes\n", envelope.PayloadLength)
+		_, _ = fmt.Fprintf(tw, "block aligned:\t%t\n", envelope.BlockAligned)
+		_, _ = fmt.Fprintf(tw, "layout:\t%s\n", envelope.Layout)
+	}
+	for _, stage := range envelope.Stages {
+		result := "ok"
+		if !stage.OK {
+			result = "failed: " + stage.Error
+		}
+		_, _ = fmt.Fprintf(tw, "%s:\t%s\n", stage.Name, result)
+	}
+	if envelope.Error != "" {
+		_, _ = fmt.Fprintf(tw, "error:\t%s\n", envelope.Error)
+	}
+}
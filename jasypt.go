@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/jasypt"
+)
+
+type jasyptFlags struct {
+	enabled      *bool
+	passwordFile *string
+	algorithm    *string
+	iterations   *int
+	prefix       *string
+	suffix       *string
+}
+
+func addJasyptFlags(fs *flag.FlagSet) *jasyptFlags {
+	return &jasyptFlags{
+		enabled:      fs.Bool("jasypt", false, "Decrypt the ENC(...) values of jasypt-spring-boot as well. The password is read from -jasypt-password-file or "+jasypt.EnvPassword+"."),
+		passwordFile: fs.String("jasypt-password-file", "", "The file with the Jasypt password."),
+		algorithm:    fs.String("jasypt-algorithm", jasypt.DefaultAlgorithm, "The Jasypt algorithm: "+jasypt.LegacyAlgorithm+" or PBEWITHHMACSHA<n>ANDAES_<bits>."),
+		iterations:   fs.Int("jasypt-iterations", jasypt.DefaultIterations, "The Jasypt key obtention iterations."),
+		prefix:       fs.String("jasypt-prefix", jasypt.DefaultPrefix, "The prefix of the Jasypt values."),
+		suffix:       fs.String("jasypt-suffix", jasypt.DefaultSuffix, "The suffix of the Jasypt values."),
+	}
+}
+
+// decryptor returns the Jasypt value decryptor, nil if -jasypt is not set.
+func (f *jasyptFlags) decryptor() (*jasypt.ValueDecryptor, error) {
+	if !*f.enabled {
+		return nil, nil
+	}
+	var password []byte
+	if *f.passwordFile != "" {
+		var err error
+		if password, err = readSecretFile(*f.passwordFile); err != nil {
+			return nil, err
+		}
+	} else if password = []byte(os.Getenv(jasypt.EnvPassword)); len(password) == 0 {
+		return nil, errors.New("missing jasypt password, provide it in the env variable " + jasypt.EnvPassword + " or use -jasypt-password-file flag")
+	}
+	return jasypt.NewValueDecryptor(password,
+		jasypt.WithAlgorithm(*f.algorithm),
+		jasypt.WithIterations(*f.iterations),
+		jasypt.WithWrapper(*f.prefix, *f.suffix),
+	)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/keys"
+)
+
+func runKeygen(args []string) {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	var (
+		bits              = fs.Int("bits", 4096, fmt.Sprintf("The RSA key size in bits, at least %d.", keys.MinBits))
+		format            = fs.String("format", string(keys.FormatPKCS1), "The private key format: pkcs1 or pkcs8.")
+		passphraseFile    = fs.String("passphrase-file", "", "The file with the passphrase to encrypt the private key with, requires -format=pkcs8.")
+		output            = fs.String("o", "-", `The file to write the private key to. Use '-' for stdout.`)
+		publicKeyFile     = fs.String("pub", "", "The file to write the PKIX public key to.")
+		keystoreFile      = fs.String("keystore", "", "The PKCS#12 or JKS keystore file to write the key pair to, for the Config Server encrypt.key-store properties.")
+		keystoreType      = fs.String("keystore-type", "", "The keystore type: pkcs12 or jks. If empty jks is used for .jks files and pkcs12 otherwise.")
+		storePasswordFile = fs.String("store-password-file", "", "The file with the password of the keystore and of the key entry.")
+		alias             = fs.String("alias", "config-server", "The alias of the keystore entry.")
+		commonName        = fs.String("cn", "spring-config-decryptor", "The common name of the self-signed certificate of the keystore entry.")
+		validity          = fs.Int("validity", 3650, "The validity of the self-signed certificate in days.")
+		force             = fs.Bool("force", false, "Overwrite existing files.")
+	)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s keygen [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	keyFormat, err := keys.ParseFormat(*format)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	var passphrase []byte
+	if *passphraseFile != "" {
+		if passphrase, err = readSecretFile(*passphraseFile); err != nil {
+			exitOnError("passphrase %v", err)
+		}
+	}
+	if *keystoreType == "" {
+		*keystoreType = "pkcs12"
+		if strings.EqualFold(filepath.Ext(*keystoreFile), ".jks") {
+			*keystoreType = "jks"
+		}
+	}
+	var storePassword []byte
+	if *keystoreFile != "" {
+		if *keystoreType != "pkcs12" && *keystoreType != "jks" {
+			exitOnError("unsupported keystore type '%s', expected pkcs12 or jks", *keystoreType)
+		}
+		if *storePasswordFile == "" {
+			exitOnError("keystore requires -store-password-file")
+		}
+		if storePassword, err = readSecretFile(*storePasswordFile); err != nil {
+			exitOnError("store password %v", err)
+		}
+	}
+
+	key, err := keys.Generate(*bits)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	privateKey, err := keys.EncodePrivateKey(key, keyFormat, passphrase)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	if err = writeKeyFile(*output, privateKey, 0600, *force); err != nil {
+		exitOnError("private key write error: %v", err)
+	}
+	if *publicKeyFile != "" {
+		publicKey, err := keys.EncodePublicKey(&key.PublicKey, keys.FormatPKIX)
+		if err != nil {
+			exitOnError("%v", err)
+		}
+		if err = writeKeyFile(*publicKeyFile, publicKey, 0644, *force); err != nil {
+			exitOnError("public key write error: %v", err)
+		}
+	}
+	if *keystoreFile != "" {
+		certificate, err := keys.SelfSignedCertificate(key, *commonName, time.Duration(*validity)*24*time.Hour)
+		if err != nil {
+			exitOnError("%v", err)
+		}
+		var keystore []byte
+		if *keystoreType == "jks" {
+			keystore, err = keys.EncodeJKS(key, certificate, *alias, storePassword, time.Now())
+		} else {
+			keystore, err = keys.EncodePKCS12(key, certificate, *alias, storePassword)
+		}
+		if err != nil {
+			exitOnError("keystore encoding error: %v", err)
+		}
+		if err = writeKeyFile(*keystoreFile, keystore, 0600, *force); err != nil {
+			exitOnError("keystore write error: %v", err)
+		}
+	}
+	fingerprint, err := decryptor.Fingerprint(&key.PublicKey)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "fingerprint: %s\n", fingerprint)
+}
+
+// readSecretFile reads a passphrase or password without the trailing line break.
+func readSecretFile(name string) ([]byte, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("file reading error: %v", err)
+	}
+	data = []byte(strings.TrimRight(string(data), "\r\n"))
+	if len(data) == 0 {
+		return nil, fmt.Errorf("file %s is empty", name)
+	}
+	return data, nil
+}
+
+// writeKeyFile writes the key to stdout or to a new file, existing files are overwritten only if forced.
+func writeKeyFile(name string, data []byte, perm os.FileMode, force bool) error {
+	if name == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(name, flags, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/kms"
+)
+
+type kmsFlags struct {
+	enabled  *bool
+	endpoint *string
+	region   *string
+	keyID    *string
+}
+
+func addKMSFlags(fs *flag.FlagSet) *kmsFlags {
+	return &kmsFlags{
+		enabled:  fs.Bool("kms", false, "Decrypt the {cipher} values which are not RSA envelopes with AWS KMS, as written by spring-cloud-config-aws-kms. The credentials are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN."),
+		endpoint: fs.String("kms-endpoint", "", "The KMS endpoint. If empty https://kms.<region>.amazonaws.com is used."),
+		region:   fs.String("kms-region", "", "The KMS region. If empty AWS_REGION / AWS_DEFAULT_REGION is used."),
+		keyID:    fs.String("kms-key-id", "", "The KMS key id, ARN or alias for values without {key:} prefix, required for asymmetric keys only."),
+	}
+}
+
+// decryptor returns the KMS value decryptor, nil if -kms is not set.
+func (f *kmsFlags) decryptor() (decryptor.CipherDecryptor, error) {
+	if !*f.enabled {
+		return nil, nil
+	}
+	credentials, err := kms.CredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	region := *f.region
+	if region == "" {
+		region = kms.RegionFromEnv()
+	}
+	var options []kms.ClientOption
+	if *f.endpoint != "" {
+		options = append(options, kms.WithEndpoint(*f.endpoint))
+	}
+	client, err := kms.NewClient(region, credentials, options...)
+	if err != nil {
+		return nil, err
+	}
+	return kms.NewValueDecryptor(client, kms.WithKeyID(*f.keyID))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/lint"
+)
+
+func runLint(args []string) {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	var (
+		sensitive = fs.String("sensitive", strings.Join(lint.DefaultSensitivePatterns, ","), "The comma separated list of sensitive property name patterns.")
+		allowlist = fs.String("allowlist", "", "The file with allowed properties, one 'property-glob' or 'file-glob:property-glob' per line.")
+		entropy   = fs.Float64("entropy", 3.5, "The entropy in bits per character above which a value looks like a secret. Use 0 to disable.")
+		format    = fs.String("format", "text", "The report format: text, json or sarif.")
+		output    = fs.String("o", "-", `The file to write the report to. Use '-' for stdout.`)
+	)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s lint [flags] <file or directory>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := validateReportFormat(*format); err != nil {
+		exitOnError("%v", err)
+	}
+
+	options := []lint.Option{
+		lint.WithSensitivePatterns(strings.Split(*sensitive, ",")),
+		lint.WithEntropyThreshold(*entropy),
+	}
+	if *allowlist != "" {
+		f, err := os.Open(*allowlist)
+		if err != nil {
+			exitOnError("allowlist open file error: %v", err)
+		}
+		options = append(options, lint.WithAllowlist(f))
+		defer f.Close()
+	}
+	linter, err := lint.New(options...)
+	if err != nil {
+		exitOnError("create linter error: %v", err)
+	}
+
+	report := findingsReport{}
+	err = walkFiles(fs.Args(), func(path string, content []byte) error {
+		if !lint.Supported(path) {
+			return nil
+		}
+		report.Files++
+		for _, f := range linter.Lint(path, content) {
+			report.Findings = append(report.Findings, finding{
+				RuleID:  string(f.Rule),
+				Level:   "error",
+				File:    f.File,
+				Line:    f.Line,
+				Column:  f.Column,
+				Path:    f.Path,
+				Message: f.Message,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		exitOnError("lint error: %v", err)
+	}
+
+	out, closeOutput, err := openOutput(*output, true)
+	if err != nil {
+		exitOnError("output open file error: %v", err)
+	}
+	if err = writeFindings(out, *format, lintRules(), report); err != nil {
+		exitOnError("report write error: %v", err)
+	}
+	closeOutput()
+	if len(report.Findings) != 0 {
+		os.Exit(1)
+	}
+}
+
+func lintRules() []rule {
+	var rules []rule
+	for id, description := range lint.Rules {
+		rules = append(rules, rule{ID: string(id), Description: description})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
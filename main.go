@@ -1,12 +1,13 @@
 package main
 
 import (
-	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"sort"
 
 	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
 )
@@ -17,73 +18,210 @@ const (
 )
 
 var (
-	inputFile  = flag.String("f", "-", `The file name to decrypt. Use '-' for stdin.`)
-	outputFile = flag.String("o", "-", `The file to write the result to. Use '-' for stdout.`)
-	keyFile    = flag.String("k", "", fmt.Sprintf("The file with RSA private key. If empty the key is read from environment variable %s / %s", defaultEnvEncryptKey, defaultEnvEncryptKeyBase64))
+	inputFile   = flag.String("f", "-", `The file name to decrypt. Use '-' for stdin.`)
+	outputFile  = flag.String("o", "-", `The file to write the result to. Use '-' for stdout.`)
+	keyFile     = flag.String("k", "", keyFlagUsage)
+	configFile  = flag.String("config", "", configFlagUsage)
+	onError     = flag.String("on-error", decryptor.FailFast.String(), "The handling of values which cannot be decrypted: fail, keep, placeholder or drop.")
+	placeholder = flag.String("placeholder", "<n/a>", "The replacement of values which cannot be decrypted when -on-error=placeholder.")
+	redact      = flag.String("redact", "", "Write redacted values instead of the plain text: fingerprint or mask. The values are still decrypted to verify them.")
+	redactKey   = flag.String("redact-key", "", "The HMAC key of the fingerprints when -redact=fingerprint. If empty plain SHA-256 is used.")
+	mask        = flag.String("mask", "******", "The replacement of the values when -redact=mask.")
 )
 
+var (
+	keyringFiles  stringsFlag
+	kmsOptions    = addKMSFlags(flag.CommandLine)
+	jasyptOptions = addJasyptFlags(flag.CommandLine)
+	errMissingKey = errors.New("missing private key error")
+)
+
+const keyringFlagUsage = "The file with a candidate RSA private key tried after the -k key, can be repeated. The keys are tried in the given order and the fingerprint of the key which decrypted the values is reported."
+
+func init() {
+	flag.Var(&keyringFiles, "keyring", keyringFlagUsage)
+}
+
+var keyFlagUsage = fmt.Sprintf("The RSA private key source: a file or a file://, env://NAME, fd://N, stdin://, dir:// or mount:// URI. A stdin key (stdin:// or '-') cannot be used with input from stdin. If empty the key is read from environment variable %s / %s or from the file in %s_FILE / %s_FILE", defaultEnvEncryptKey, defaultEnvEncryptKeyBase64, defaultEnvEncryptKey, defaultEnvEncryptKeyBase64)
+
+type command struct {
+	usage string
+	run   func(args []string)
+}
+
+var commands = map[string]command{
+	"check":        {usage: "Check that every {cipher} value in the files and directories can be decrypted", run: runCheck},
+	"configtree":   {usage: "Write the decrypted properties as a directory with one file per property, replaced atomically", run: runConfigTree},
+	"diff":         {usage: "Print the property level diff of two configuration versions with decrypted values", run: runDiff},
+	"edit":         {usage: "Edit a file with {cipher} values in $EDITOR, re-encrypting only the changed values", run: runEdit},
+	"encrypt-file": {usage: "Encrypt the values of a YAML, properties or JSON file selected by their property paths", run: runEncryptFile},
+	"fetch":        {usage: "Fetch the configuration from a Spring Cloud Config Server and decrypt it", run: runFetch},
+	"fingerprint":  {usage: "Print the SHA-256 fingerprint of a public key to confirm which key a deployment holds", run: runFingerprint},
+	"fix":          {usage: "Quote and normalize {cipher} values in YAML and properties files in place", run: runFix},
+	"get":          {usage: "Print a single decrypted property of a YAML, properties or JSON file by its Spring path", run: runGet},
+	"git-env":      {usage: "Resolve the decrypted Config Server environment of an application from a local git repository and label", run: runGitEnv},
+	"git-filter":   {usage: "Git smudge/clean filter and textconv diff driver for files with {cipher} values", run: runGitFilter},
+	"inspect":      {usage: "Describe the structure of a {cipher} value and which decryption stage fails", run: runInspect},
+	"keygen":       {usage: "Generate an RSA key pair as PEM and optionally as PKCS#12 or JKS keystore", run: runKeygen},
+	"lint":         {usage: "Report sensitive properties which are not encrypted and unquoted {cipher} values", run: runLint},
+	"pubkey":       {usage: "Print the public key of a private key", run: runPubkey},
+	"render":       {usage: "Render the effective configuration of an application for its active profiles, decrypted", run: runRender},
+	"rotate":       {usage: "Re-encrypt every {cipher} value in the files and directories for a new key", run: runRotate},
+	"template":     {usage: "Render Go text/template files with the decrypted properties of config files", run: runTemplate},
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd.run(os.Args[2:])
+			return
+		}
+	}
+	flag.Usage = usage
 	flag.Parse()
 
-	var (
-		key []byte
-		err error
-	)
+	errorPolicy, err := decryptor.ParseErrorPolicy(*onError)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	valueDecryptors, err := newValueDecryptors(flag.CommandLine, *configFile, *inputFile, *keyFile, keyringFiles, kmsOptions, jasyptOptions)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+
+	input, closeInput, err := openInput(*inputFile)
+	if err != nil {
+		exitOnError("input open file error: %v", err)
+	}
+	defer closeInput()
 
-	if len(*keyFile) != 0 {
-		key, err = ioutil.ReadFile(*keyFile)
-		if err != nil {
-			exitOnError("key file reading error: %v", err)
-		}
-	} else if value := os.Getenv(defaultEnvEncryptKey); value != "" {
-		key = []byte(value)
-	} else if value = os.Getenv(defaultEnvEncryptKeyBase64); value != "" {
-		key, err = base64.StdEncoding.DecodeString(value)
-		if err != nil {
-			exitOnError("key file reading error: %v", err)
-		}
-	} else {
-		exitOnError("missing private key error, provide key in the env variable %s / %s or use -k flag", defaultEnvEncryptKey, defaultEnvEncryptKeyBase64)
+	output, closeOutput, err := openOutput(*outputFile, false)
+	if err != nil {
+		exitOnError("output open file error: %v", err)
 	}
+	defer closeOutput()
 
-	var input io.Reader
-	if *inputFile == "-" {
-		input = os.Stdin
-	} else {
-		f, err := os.Open(*inputFile)
-		if err != nil {
-			exitOnError("input open file error: %v", err)
-		}
-		defer f.Close()
-		input = f
+	options := []decryptor.ConfigDecryptorOption{decryptor.WithErrorPolicy(errorPolicy), decryptor.WithPlaceholder(*placeholder)}
+	switch *redact {
+	case "":
+	case "fingerprint":
+		options = append(options, decryptor.WithRedactor(decryptor.FingerprintRedactor([]byte(*redactKey))))
+	case "mask":
+		options = append(options, decryptor.WithRedactor(decryptor.MaskRedactor(*mask)))
+	default:
+		exitOnError("unknown redaction '%s', expected fingerprint or mask", *redact)
+	}
+	dcr := decryptor.NewSchemeDecryptor(valueDecryptors.schemes, options...)
+	report, err := dcr.DecryptWithReport(output, input)
+	if err != nil {
+		exitOnError("decrypt error: %v", err)
 	}
+	for _, failure := range report.Failures {
+		_, _ = fmt.Fprintf(os.Stderr, "warning: %s\n", failure)
+	}
+	valueDecryptors.writeKeyUsage(report.Keys)
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	_, _ = fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+	flag.PrintDefaults()
+	_, _ = fmt.Fprintf(out, "\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(out, "  %-12s %s\n", name, commands[name].usage)
+	}
+}
+
+// keyProvider returns the provider of the key source, by default the environment variables.
+func keyProvider(source string) (decryptor.KeyProvider, error) {
+	if source != "" {
+		return decryptor.ParseKeyProvider(source)
+	}
+	return decryptor.NewFirstKeyProvider(
+		decryptor.NewEnvKeyProvider(defaultEnvEncryptKey, false),
+		decryptor.NewEnvKeyProvider(defaultEnvEncryptKeyBase64, true),
+	), nil
+}
+
+// readKeys reads the private keys from the key source or from the environment variables.
+func readKeys(source string) ([][]byte, error) {
+	provider, err := keyProvider(source)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := provider.Keys()
+	if source == "" && errors.Is(err, decryptor.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w, provide key in the env variable %s / %s or use -k flag", errMissingKey, defaultEnvEncryptKey, defaultEnvEncryptKeyBase64)
+	}
+	return keys, err
+}
+
+// readKey reads the first private key from the key source or from the environment variables.
+func readKey(source string) ([]byte, error) {
+	keys, err := readKeys(source)
+	if err != nil {
+		return nil, err
+	}
+	return keys[0], nil
+}
 
-	var output io.Writer
-	if *outputFile == "-" {
-		output = os.Stdout
-	} else {
-		// do not truncate, append if already exists
-		f, err := os.OpenFile(*outputFile, os.O_WRONLY|os.O_CREATE, 0664)
+// readKeyring returns the keyring of the further keys of the key source and of the -keyring files,
+// no options are returned without keys.
+func readKeyring(keys [][]byte, files []string) ([]decryptor.ValueDecryptorOption, error) {
+	if len(keys) == 0 && len(files) == 0 {
+		return nil, nil
+	}
+	for _, file := range files {
+		key, err := ioutil.ReadFile(file)
 		if err != nil {
-			exitOnError("output open file error: %v", err)
+			return nil, fmt.Errorf("keyring file reading error: %v", err)
 		}
-		defer func() {
-			if err := f.Close(); err != nil {
-				exitOnError("output close error: %v", err)
-			}
-		}()
-		output = f
+		keys = append(keys, key)
 	}
+	return []decryptor.ValueDecryptorOption{decryptor.WithKeyring(keys...)}, nil
+}
+
+// writeKeyUsage prints the number of values each key decrypted in the order the keys are tried.
+func writeKeyUsage(w io.Writer, fingerprints []string, keys map[string]int) {
+	for _, fingerprint := range fingerprints {
+		_, _ = fmt.Fprintf(w, "key %s decrypted %d values\n", fingerprint, keys[fingerprint])
+	}
+}
 
-	dcr, err := decryptor.NewDecryptor(key)
+func openInput(name string) (io.Reader, func(), error) {
+	if name == "-" {
+		return os.Stdin, func() {}, nil
+	}
+	f, err := os.Open(name)
 	if err != nil {
-		exitOnError("create decryptor error: %v", err)
-		return
+		return nil, nil, err
+	}
+	return f, func() { _ = f.Close() }, nil
+}
+
+func openOutput(name string, truncate bool) (io.Writer, func(), error) {
+	if name == "-" {
+		return os.Stdout, func() {}, nil
 	}
-	err = dcr.Decrypt(output, input)
+	// do not truncate by default, append if already exists
+	flags := os.O_WRONLY | os.O_CREATE
+	if truncate {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(name, flags, 0664)
 	if err != nil {
-		exitOnError("decrypt error: %v", err)
+		return nil, nil, err
 	}
+	return f, func() {
+		if err := f.Close(); err != nil {
+			exitOnError("output close error: %v", err)
+		}
+	}, nil
 }
 
 func exitOnError(format string, a ...interface{}) {
@@ -0,0 +1,279 @@
+// Package configdata resolves the configuration files of a Spring Boot application into its environment the way
+// Spring Boot's config data processing does: file and document ordering, profile-specific files and profile
+// activation of multi-document files.
+package configdata
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/configserver"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultName is the name of the configuration files shared by all applications.
+	DefaultName = "application"
+	// DefaultProfile is active if no other profile is.
+	DefaultProfile = "default"
+)
+
+// extensions are the supported file extensions from the lowest precedence, .properties wins over YAML.
+var extensions = []string{".yaml", ".yml", ".properties"}
+
+var (
+	onProfileKey = regexp.MustCompile(`^spring\.config\.activate\.on-profile(\[\d+])?$`)
+	legacyKey    = regexp.MustCompile(`^spring\.profiles(\[\d+])?$`)
+	groupKey     = regexp.MustCompile(`^spring\.profiles\.group\.(.+?)(\[\d+])?$`)
+)
+
+// ReadFile reads a configuration file by its slash separated path, a missing file is reported by an error
+// for which os.IsNotExist is true.
+type ReadFile func(name string) ([]byte, error)
+
+// DirReadFile reads the configuration files from the directory.
+func DirReadFile(dir string) ReadFile {
+	return func(name string) ([]byte, error) {
+		return ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	}
+}
+
+type LoaderOption func(*Loader) error
+
+// Loader loads the environment of an application from its configuration files.
+type Loader struct {
+	readFile  ReadFile
+	locations []string
+}
+
+// NewLoader creates a loader of the files read by readFile, by default from the locations "" and "config".
+func NewLoader(readFile ReadFile, options ...LoaderOption) (*Loader, error) {
+	loader := &Loader{
+		readFile:  readFile,
+		locations: []string{"", "config"},
+	}
+	for _, option := range options {
+		if err := option(loader); err != nil {
+			return nil, err
+		}
+	}
+	return loader, nil
+}
+
+// WithLocations sets the slash separated directories of the configuration files, later locations win.
+func WithLocations(locations ...string) LoaderOption {
+	return func(loader *Loader) error {
+		if len(locations) == 0 {
+			return errors.New("at least one location is required")
+		}
+		loader.locations = locations
+		return nil
+	}
+}
+
+// document is a single document of a configuration file.
+type document struct {
+	name  string
+	props properties.Properties
+	// onProfile is the activation condition, nil if the document is always active
+	onProfile profileExpression
+}
+
+// Load resolves the environment of the application with the given active profiles, the shared application files
+// have a lower precedence than the files of the application. Without profiles the profiles are activated by
+// spring.profiles.active of the files. The property sources of the environment are ordered from the highest
+// precedence as in a Config Server response.
+func (l *Loader) Load(application string, profiles []string) (*configserver.Environment, error) {
+	names := []string{DefaultName}
+	if application != "" && application != DefaultName {
+		names = append(names, application)
+	}
+	base := make([][]document, len(l.locations))
+	var unconditional []document
+	for i, location := range l.locations {
+		for _, name := range names {
+			documents, err := l.readDocuments(location, name)
+			if err != nil {
+				return nil, err
+			}
+			base[i] = append(base[i], documents...)
+		}
+		for _, d := range base[i] {
+			if d.onProfile == nil {
+				unconditional = append(unconditional, d)
+			}
+		}
+	}
+	active := activeProfiles(unconditional, profiles)
+	activeSet := make(map[string]bool)
+	for _, profile := range active {
+		activeSet[profile] = true
+	}
+
+	var sources []configserver.PropertySource
+	add := func(documents []document) {
+		for _, d := range documents {
+			if d.onProfile == nil || d.onProfile(activeSet) {
+				sources = append(sources, configserver.PropertySource{Name: d.name, Source: d.props})
+			}
+		}
+	}
+	// the locations form one group: the base documents of all locations precede the profile-specific ones
+	for i := range l.locations {
+		add(base[i])
+	}
+	for _, location := range l.locations {
+		for _, profile := range active {
+			for _, name := range names {
+				documents, err := l.readDocuments(location, name+"-"+profile)
+				if err != nil {
+					return nil, err
+				}
+				add(documents)
+			}
+		}
+	}
+	for i, j := 0, len(sources)-1; i < j; i, j = i+1, j-1 {
+		sources[i], sources[j] = sources[j], sources[i]
+	}
+	if application == "" {
+		application = DefaultName
+	}
+	return &configserver.Environment{Name: application, Profiles: active, PropertySources: sources}, nil
+}
+
+// readDocuments reads the documents of the files with the base name in the location in the order of precedence.
+func (l *Loader) readDocuments(location, name string) ([]document, error) {
+	var result []document
+	for _, extension := range extensions {
+		fileName := path.Join(location, name+extension)
+		content, err := l.readFile(fileName)
+		if err != nil {
+			if os.IsNotExist(errors.Cause(err)) {
+				continue
+			}
+			return nil, fmt.Errorf("%s: %v", fileName, err)
+		}
+		documents, err := properties.ReadDocuments(bytes.NewReader(content), properties.FormatOf(fileName))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fileName, err)
+		}
+		for i, props := range documents {
+			if len(props) == 0 {
+				continue
+			}
+			d := document{name: fileName, props: props}
+			if len(documents) > 1 {
+				d.name = fmt.Sprintf("%s (document #%d)", fileName, i)
+			}
+			if d.onProfile, err = activation(props); err != nil {
+				return nil, fmt.Errorf("%s: %v", d.name, err)
+			}
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+// activation returns the condition of spring.config.activate.on-profile or of the legacy spring.profiles.
+func activation(props properties.Properties) (profileExpression, error) {
+	var expressions []string
+	for _, key := range props.Keys() {
+		if onProfileKey.MatchString(key) || legacyKey.MatchString(key) {
+			expressions = append(expressions, properties.ToString(props[key]))
+		}
+	}
+	if len(expressions) == 0 {
+		return nil, nil
+	}
+	return parseProfiles(strings.Join(expressions, ","))
+}
+
+// activeProfiles returns the given profiles or those of spring.profiles.active, preceded by spring.profiles.include
+// and followed by the members of their groups. If none is active the default profile is.
+func activeProfiles(documents []document, profiles []string) []string {
+	var (
+		active, include, defaults []string
+		groups                    = make(map[string][]string)
+	)
+	for _, d := range documents {
+		if values := listValue(d.props, "spring.profiles.active"); values != nil {
+			active = values
+		}
+		if values := listValue(d.props, "spring.profiles.default"); values != nil {
+			defaults = values
+		}
+		include = append(include, listValue(d.props, "spring.profiles.include")...)
+		for _, key := range d.props.Keys() {
+			if m := groupKey.FindStringSubmatch(key); m != nil {
+				groups[m[1]] = listValue(d.props, "spring.profiles.group."+m[1])
+			}
+		}
+	}
+	if len(profiles) != 0 {
+		active = profiles
+	}
+	candidates := append(include, active...)
+	if len(candidates) == 0 {
+		candidates = defaults
+		if len(candidates) == 0 {
+			candidates = []string{DefaultProfile}
+		}
+	}
+	var (
+		result []string
+		seen   = make(map[string]bool)
+		expand func(profile string)
+	)
+	expand = func(profile string) {
+		if profile == "" || seen[profile] {
+			return
+		}
+		seen[profile] = true
+		result = append(result, profile)
+		for _, member := range groups[profile] {
+			expand(member)
+		}
+	}
+	for _, profile := range candidates {
+		expand(profile)
+	}
+	return result
+}
+
+// listValue returns the comma separated value or the list of the key, nil if it is not set.
+func listValue(props properties.Properties, key string) []string {
+	var result []string
+	if value, ok := props[key]; ok {
+		result = append([]string{}, SplitProfiles(properties.ToString(value))...)
+	}
+	for i := 0; ; i++ {
+		value, ok := props[fmt.Sprintf("%s[%d]", key, i)]
+		if !ok {
+			break
+		}
+		if result == nil {
+			result = []string{}
+		}
+		result = append(result, strings.TrimSpace(properties.ToString(value)))
+	}
+	return result
+}
+
+// SplitProfiles splits the comma separated list of profiles.
+func SplitProfiles(value string) []string {
+	var result []string
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
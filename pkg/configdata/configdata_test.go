@@ -0,0 +1,181 @@
+package configdata
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+)
+
+func mapReadFile(files map[string]string) ReadFile {
+	return func(name string) ([]byte, error) {
+		content, ok := files[name]
+		if !ok {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		return []byte(content), nil
+	}
+}
+
+func TestParseProfiles(t *testing.T) {
+	tests := []struct {
+		expression string
+		active     []string
+		expected   bool
+		err        string
+	}{
+		{expression: "dev", active: []string{"dev"}, expected: true},
+		{expression: "dev", active: []string{"prod"}, expected: false},
+		{expression: "!prod", active: []string{"dev"}, expected: true},
+		{expression: "dev, eu", active: []string{"eu"}, expected: true},
+		{expression: "dev & eu", active: []string{"eu"}, expected: false},
+		{expression: "dev & eu", active: []string{"dev", "eu"}, expected: true},
+		{expression: "(dev | test) & !eu", active: []string{"test"}, expected: true},
+		{expression: "(dev | test) & !eu", active: []string{"test", "eu"}, expected: false},
+		{expression: "dev & eu | us", err: "invalid profile expression 'dev & eu | us': mixed '&' and '|' require parentheses"},
+		{expression: "(dev", err: "invalid profile expression '(dev': missing ')'"},
+		{expression: "dev &", err: "invalid profile expression 'dev &': missing profile"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expression, func(t *testing.T) {
+			expression, err := parseProfiles(tc.expression)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected error %q, actual %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			active := make(map[string]bool)
+			for _, profile := range tc.active {
+				active[profile] = true
+			}
+			if actual := expression(active); actual != tc.expected {
+				t.Errorf("Expected %v, actual %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	files := map[string]string{
+		"application.yml": `
+server.port: 8080
+db:
+  url: jdbc:base
+  user: app
+spring.profiles.group.eu: eu-west
+---
+spring.config.activate.on-profile: dev
+db.url: jdbc:dev-doc
+log: debug
+---
+spring:
+  profiles: "!dev"
+log: info
+`,
+		"application.properties":     "server.port=8081\n",
+		"orders.yml":                 "db.user: orders\n",
+		"application-dev.yml":        "db.url: jdbc:dev\ncache: dev\n",
+		"application-eu.properties":  "region=eu\ncache=eu\n#---\nspring.config.activate.on-profile=dev & eu-west\nzone=dev-eu\n",
+		"orders-dev.yml":             "db.user: orders-dev\n",
+		"config/application-dev.yml": "cache: config-dev\n",
+		"application-prod.yml":       "db.url: jdbc:prod\n",
+	}
+	tests := []struct {
+		name        string
+		application string
+		files       map[string]string
+		profiles    []string
+		expected    properties.Properties
+		active      []string
+		sources     []string
+	}{
+		{
+			name:        "default",
+			application: "orders",
+			files:       files,
+			expected: properties.Properties{
+				"server.port": "8081", "db.url": "jdbc:base", "db.user": "orders", "log": "info",
+				"spring.profiles": "!dev", "spring.profiles.group.eu": "eu-west",
+			},
+			active:  []string{"default"},
+			sources: []string{"orders.yml", "application.properties", "application.yml (document #2)", "application.yml (document #0)"},
+		},
+		{
+			name:        "profiles",
+			application: "orders",
+			files:       files,
+			profiles:    []string{"dev", "eu"},
+			expected: properties.Properties{
+				"server.port": "8081", "db.url": "jdbc:dev", "db.user": "orders-dev", "log": "debug", "cache": "config-dev",
+				"region": "eu", "zone": "dev-eu", "spring.config.activate.on-profile": "dev & eu-west",
+				"spring.profiles.group.eu": "eu-west",
+			},
+			active: []string{"dev", "eu", "eu-west"},
+			sources: []string{
+				"config/application-dev.yml",
+				"application-eu.properties (document #1)", "application-eu.properties (document #0)",
+				"orders-dev.yml", "application-dev.yml",
+				"orders.yml", "application.properties", "application.yml (document #1)", "application.yml (document #0)",
+			},
+		},
+		{
+			name:  "active and include",
+			files: map[string]string{"application.yml": "spring.profiles.active: prod\nspring.profiles.include: [common]\n", "application-prod.yml": "a: prod\n", "application-common.yml": "a: common\nb: common\n"},
+			expected: properties.Properties{
+				"a": "prod", "b": "common", "spring.profiles.active": "prod", "spring.profiles.include[0]": "common",
+			},
+			active:  []string{"common", "prod"},
+			sources: []string{"application-prod.yml", "application-common.yml", "application.yml"},
+		},
+		{
+			name:     "profile file overrides base file of a later location",
+			files:    map[string]string{"application.yml": "a: root\n", "application-dev.yml": "a: root-dev\n", "config/application.yml": "a: config-base\nb: config-base\n"},
+			profiles: []string{"dev"},
+			expected: properties.Properties{"a": "root-dev", "b": "config-base"},
+			active:   []string{"dev"},
+			sources:  []string{"application-dev.yml", "config/application.yml", "application.yml"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			loader, err := NewLoader(mapReadFile(tc.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			env, err := loader.Load(tc.application, tc.profiles)
+			if err != nil {
+				t.Fatalf("load error: %v", err)
+			}
+			var sources []string
+			for _, ps := range env.PropertySources {
+				sources = append(sources, ps.Name)
+			}
+			if !reflect.DeepEqual(sources, tc.sources) {
+				t.Errorf("Sources differ: expected %v, actual %v", tc.sources, sources)
+			}
+			if !reflect.DeepEqual(env.Profiles, tc.active) {
+				t.Errorf("Profiles differ: expected %v, actual %v", tc.active, env.Profiles)
+			}
+			if actual := env.Properties(); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Properties differ: expected %v, actual %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLoadInvalidProfile(t *testing.T) {
+	loader, err := NewLoader(mapReadFile(map[string]string{"application.yml": "a: 1\n---\nspring.config.activate.on-profile: a & b | c\n"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loader.Load("", nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "application.yml (document #1): invalid profile expression") {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
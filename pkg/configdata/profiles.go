@@ -0,0 +1,149 @@
+package configdata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// profileExpression is a parsed Spring profile expression like "dev", "!prod" or "(eu | us) & prod".
+type profileExpression func(active map[string]bool) bool
+
+// parseProfiles parses the comma separated profile expressions of spring.config.activate.on-profile,
+// the document is active if any of them matches.
+func parseProfiles(value string) (profileExpression, error) {
+	var expressions []profileExpression
+	for _, s := range strings.Split(value, ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		p := &profileParser{tokens: tokenizeProfiles(s)}
+		expression, err := p.parse()
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile expression '%s': %v", strings.TrimSpace(s), err)
+		}
+		expressions = append(expressions, expression)
+	}
+	if len(expressions) == 0 {
+		return nil, fmt.Errorf("invalid profile expression '%s'", value)
+	}
+	return func(active map[string]bool) bool {
+		for _, expression := range expressions {
+			if expression(active) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func tokenizeProfiles(s string) []string {
+	var (
+		tokens []string
+		name   strings.Builder
+	)
+	flush := func() {
+		if name.Len() != 0 {
+			tokens = append(tokens, name.String())
+			name.Reset()
+		}
+	}
+	for _, r := range s {
+		switch r {
+		case '(', ')', '&', '|', '!':
+			flush()
+			tokens = append(tokens, string(r))
+		case ' ', '\t':
+			flush()
+		default:
+			name.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// profileParser parses the grammar of Spring's Profiles.of, & and | must not be mixed without parentheses.
+type profileParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *profileParser) parse() (profileExpression, error) {
+	expression, err := p.parseOperation()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s'", p.tokens[p.pos])
+	}
+	return expression, nil
+}
+
+func (p *profileParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *profileParser) parseOperation() (profileExpression, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	operator := p.peek()
+	if operator != "&" && operator != "|" {
+		return first, nil
+	}
+	operands := []profileExpression{first}
+	for p.peek() == "&" || p.peek() == "|" {
+		if p.peek() != operator {
+			return nil, fmt.Errorf("mixed '&' and '|' require parentheses")
+		}
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+	and := operator == "&"
+	return func(active map[string]bool) bool {
+		for _, operand := range operands {
+			if operand(active) != and {
+				return !and
+			}
+		}
+		return and
+	}, nil
+}
+
+func (p *profileParser) parseUnary() (profileExpression, error) {
+	switch token := p.peek(); token {
+	case "":
+		return nil, fmt.Errorf("missing profile")
+	case "!":
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(active map[string]bool) bool { return !operand(active) }, nil
+	case "(":
+		p.pos++
+		operation, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.pos++
+		return operation, nil
+	case ")", "&", "|":
+		return nil, fmt.Errorf("unexpected '%s'", token)
+	default:
+		p.pos++
+		return func(active map[string]bool) bool { return active[token] }, nil
+	}
+}
@@ -0,0 +1,203 @@
+package configdiff
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+	"github.com/pkg/errors"
+)
+
+// Kind is the kind of a property change.
+type Kind string
+
+const (
+	Added    Kind = "added"
+	Removed  Kind = "removed"
+	Modified Kind = "modified"
+	// ReEncrypted is a value with a new cipher text and the same plain text
+	ReEncrypted Kind = "re-encrypted"
+)
+
+// Value is a decrypted property value.
+type Value struct {
+	// Text is the plain text of the value
+	Text string
+	// CipherText is the original value if it contained {cipher} values
+	CipherText string
+}
+
+// Encrypted reports whether the value was encrypted.
+func (v Value) Encrypted() bool {
+	return v.CipherText != ""
+}
+
+// Change is the change of a single property, Old is nil for added and New for removed properties.
+type Change struct {
+	Path string
+	Kind Kind
+	Old  *Value
+	New  *Value
+}
+
+// Sensitive reports whether any side of the change was encrypted.
+func (c Change) Sensitive() bool {
+	return (c.Old != nil && c.Old.Encrypted()) || (c.New != nil && c.New.Encrypted())
+}
+
+// TextDecryptor replaces the encrypted values in a property value, like decryptor.ConfigDecryptor.
+type TextDecryptor interface {
+	DecryptText(text string) (string, error)
+}
+
+// Load parses the configuration file and decrypts its values.
+func Load(fileName string, content []byte, textDecryptor TextDecryptor) (map[string]Value, error) {
+	props, err := properties.Read(bytes.NewReader(content), properties.FormatOf(fileName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s", fileName)
+	}
+	result := make(map[string]Value, len(props))
+	for key, v := range props {
+		text := properties.ToString(v)
+		value := Value{Text: text}
+		decrypted, err := textDecryptor.DecryptText(text)
+		if err != nil {
+			return nil, &decryptor.DecryptError{File: fileName, Path: key, Err: err}
+		}
+		if decrypted != text {
+			value = Value{Text: decrypted, CipherText: text}
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// Compare returns the changes between the property sets ordered by the property path.
+func Compare(old, new map[string]Value) []Change {
+	var changes []Change
+	for path, o := range old {
+		o := o
+		n, ok := new[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: path, Kind: Removed, Old: &o})
+		case o.Text != n.Text:
+			changes = append(changes, Change{Path: path, Kind: Modified, Old: &o, New: &n})
+		case o.CipherText != n.CipherText:
+			changes = append(changes, Change{Path: path, Kind: ReEncrypted, Old: &o, New: &n})
+		}
+	}
+	for path, n := range new {
+		n := n
+		if _, ok := old[path]; !ok {
+			changes = append(changes, Change{Path: path, Kind: Added, New: &n})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// Formatter writes the changes, the values of changes with an encrypted side are masked unless revealed.
+type Formatter struct {
+	// Reveal prints the plain text of the encrypted values
+	Reveal bool
+	// Salt of the hashes of the masked values
+	Salt []byte
+}
+
+// Hash returns the short salted hash of the plain text.
+func (f Formatter) Hash(text string) string {
+	h := sha256.New()
+	_, _ = h.Write(f.Salt)
+	_, _ = h.Write([]byte(text))
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// format masks both sides of a sensitive change, so a value which is no longer or not yet encrypted is not revealed.
+func (f Formatter) format(value *Value, sensitive bool) string {
+	if !sensitive || f.Reveal {
+		return value.Text
+	}
+	return "<encrypted " + f.Hash(value.Text) + ">"
+}
+
+// WriteText writes the changes as a unified diff of properties.
+// The re-encrypted values are written as context lines.
+func (f Formatter) WriteText(w io.Writer, oldName, newName string, changes []Change) error {
+	wr := bufio.NewWriter(w)
+	if len(changes) != 0 {
+		_, _ = fmt.Fprintf(wr, "--- %s\n+++ %s\n", oldName, newName)
+	}
+	for _, change := range changes {
+		switch change.Kind {
+		case ReEncrypted:
+			_, _ = fmt.Fprintf(wr, " %s=%s (re-encrypted)\n", change.Path, f.format(change.New, true))
+		default:
+			if change.Old != nil {
+				_, _ = fmt.Fprintf(wr, "-%s=%s\n", change.Path, f.format(change.Old, change.Sensitive()))
+			}
+			if change.New != nil {
+				_, _ = fmt.Fprintf(wr, "+%s=%s\n", change.Path, f.format(change.New, change.Sensitive()))
+			}
+		}
+	}
+	return errors.Wrap(wr.Flush(), "writing flush error")
+}
+
+type jsonValue struct {
+	Value *string `json:"value,omitempty"`
+	Hash  string  `json:"hash,omitempty"`
+}
+
+type jsonChange struct {
+	Path      string     `json:"path"`
+	Change    Kind       `json:"change"`
+	Sensitive bool       `json:"sensitive"`
+	Old       *jsonValue `json:"old,omitempty"`
+	New       *jsonValue `json:"new,omitempty"`
+}
+
+type jsonDiff struct {
+	Old     string       `json:"old"`
+	New     string       `json:"new"`
+	Changes []jsonChange `json:"changes"`
+}
+
+// WriteJSON writes the changes as JSON, masked values of sensitive changes have only the hash.
+func (f Formatter) WriteJSON(w io.Writer, oldName, newName string, changes []Change) error {
+	toJSON := func(value *Value, sensitive bool) *jsonValue {
+		if value == nil {
+			return nil
+		}
+		if !sensitive {
+			return &jsonValue{Value: &value.Text}
+		}
+		result := &jsonValue{Hash: f.Hash(value.Text)}
+		if f.Reveal {
+			result.Value = &value.Text
+		}
+		return result
+	}
+	result := jsonDiff{Old: oldName, New: newName, Changes: make([]jsonChange, 0, len(changes))}
+	for _, change := range changes {
+		result.Changes = append(result.Changes, jsonChange{
+			Path:      change.Path,
+			Change:    change.Kind,
+			Sensitive: change.Sensitive(),
+			Old:       toJSON(change.Old, change.Sensitive()),
+			New:       toJSON(change.New, change.Sensitive()),
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.Wrap(enc.Encode(result), "json encoding error")
+}
@@ -0,0 +1,92 @@
+package configdiff
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/encryptor"
+)
+
+func TestDiff(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate key error: %v", err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	rsaDecryptor, err := decryptor.NewValueDecryptor(privateKey)
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	valueDecryptor := decryptor.NewConfigDecryptor(rsaDecryptor)
+	valueEncryptor, err := encryptor.NewValueEncryptor(privateKey)
+	if err != nil {
+		t.Fatalf("create value encryptor error: %v", err)
+	}
+	encrypt := func(value string) string {
+		encrypted, err := valueEncryptor.EncryptValue(value)
+		if err != nil {
+			t.Fatalf("encrypt error: %v", err)
+		}
+		return encrypted
+	}
+
+	oldValues, err := Load("application.yml", []byte("db:\n  user: admin\n  password: '"+encrypt("foo")+"'\n  token: '"+encrypt("bar")+"'\n  host: a\n  key: '"+encrypt("k1")+"'\n"), valueDecryptor)
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	newValues, err := Load("application.properties", []byte("db.user=root\ndb.password="+encrypt("foo")+"\ndb.token="+encrypt("baz")+"\ndb.port=1\ndb.key=k2\n"), valueDecryptor)
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	changes := Compare(oldValues, newValues)
+
+	formatter := Formatter{Salt: []byte("salt")}
+	var text bytes.Buffer
+	if err = formatter.WriteText(&text, "old", "new", changes); err != nil {
+		t.Fatalf("write text error: %v", err)
+	}
+	expected := "--- old\n+++ new\n" +
+		"-db.host=a\n" +
+		"-db.key=<encrypted " + formatter.Hash("k1") + ">\n" +
+		"+db.key=<encrypted " + formatter.Hash("k2") + ">\n" +
+		" db.password=<encrypted " + formatter.Hash("foo") + "> (re-encrypted)\n" +
+		"+db.port=1\n" +
+		"-db.token=<encrypted " + formatter.Hash("bar") + ">\n" +
+		"+db.token=<encrypted " + formatter.Hash("baz") + ">\n" +
+		"-db.user=admin\n" +
+		"+db.user=root\n"
+	if text.String() != expected {
+		t.Errorf("Text diff differs: expected %q, actual %q", expected, text.String())
+	}
+	if strings.Contains(text.String(), "baz") || strings.Contains(text.String(), "k2") {
+		t.Errorf("Text diff reveals the plain text")
+	}
+
+	formatter.Reveal = true
+	var revealed bytes.Buffer
+	if err = formatter.WriteJSON(&revealed, "old", "new", changes); err != nil {
+		t.Fatalf("write json error: %v", err)
+	}
+	var actual jsonDiff
+	if err = json.Unmarshal(revealed.Bytes(), &actual); err != nil {
+		t.Fatalf("json error: %v", err)
+	}
+	if len(actual.Changes) != 6 {
+		t.Fatalf("Unexpected changes %v", actual.Changes)
+	}
+	token := actual.Changes[4]
+	if token.Path != "db.token" || token.Change != Modified || !token.Sensitive || *token.Old.Value != "bar" || *token.New.Value != "baz" || token.New.Hash != formatter.Hash("baz") {
+		t.Errorf("Unexpected token change %+v", token)
+	}
+
+	if _, err = Load("application.yml", []byte("a: '{cipher}AQ=='\n"), valueDecryptor); err == nil {
+		t.Errorf("Expected decryption error")
+	}
+}
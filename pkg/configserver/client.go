@@ -0,0 +1,234 @@
+package configserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTimeout   = 10 * time.Second
+	defaultRetryWait = time.Second
+	defaultProfile   = "default"
+)
+
+// Environment is the response of the Config Server /{application}/{profile}/{label} endpoint.
+type Environment struct {
+	Name            string           `json:"name"`
+	Profiles        []string         `json:"profiles"`
+	Label           string           `json:"label,omitempty"`
+	Version         string           `json:"version,omitempty"`
+	State           string           `json:"state,omitempty"`
+	PropertySources []PropertySource `json:"propertySources"`
+}
+
+// PropertySource is a single named source of the environment, sources are ordered from the highest precedence.
+type PropertySource struct {
+	Name   string                 `json:"name"`
+	Source map[string]interface{} `json:"source"`
+}
+
+// ValueDecryptor decrypts a single property value, values which are not encrypted are returned unchanged.
+type ValueDecryptor interface {
+	DecryptValue(value string) (string, error)
+}
+
+// Decrypt decrypts the encrypted values of all property sources in place.
+func (e *Environment) Decrypt(valueDecryptor ValueDecryptor) error {
+	for _, ps := range e.PropertySources {
+		for key, value := range ps.Source {
+			s, ok := value.(string)
+			if !ok {
+				continue
+			}
+			plainText, err := valueDecryptor.DecryptValue(s)
+			if err != nil {
+				return &decryptor.DecryptError{File: ps.Name, Path: key, Err: err}
+			}
+			ps.Source[key] = plainText
+		}
+	}
+	return nil
+}
+
+// Properties merges the property sources into the effective properties, the first source wins.
+func (e *Environment) Properties() properties.Properties {
+	result := make(properties.Properties)
+	for i := len(e.PropertySources) - 1; i >= 0; i-- {
+		for key, value := range e.PropertySources[i].Source {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+type ClientOption func(client *Client) error
+
+// Client fetches environments from a Spring Cloud Config Server.
+type Client struct {
+	uri        *url.URL
+	username   string
+	password   string
+	token      string
+	retries    int
+	retryWait  time.Duration
+	httpClient *http.Client
+}
+
+func NewClient(uri string, options ...ClientOption) (*Client, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, errors.Wrapf(err, "config server uri '%s' parse error", uri)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("config server uri '%s' must be http or https", uri)
+	}
+	result := &Client{
+		uri:        parsed,
+		retryWait:  defaultRetryWait,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+	if parsed.User != nil {
+		result.username = parsed.User.Username()
+		result.password, _ = parsed.User.Password()
+		parsed.User = nil
+	}
+	for _, option := range options {
+		if err = option(result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func WithBasicAuth(username, password string) ClientOption {
+	return func(client *Client) error {
+		client.username = username
+		client.password = password
+		return nil
+	}
+}
+
+func WithToken(token string) ClientOption {
+	return func(client *Client) error {
+		client.token = token
+		return nil
+	}
+}
+
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(client *Client) error {
+		if timeout < 0 {
+			return fmt.Errorf("timeout %v must not be negative", timeout)
+		}
+		client.httpClient.Timeout = timeout
+		return nil
+	}
+}
+
+func WithRetries(retries int, wait time.Duration) ClientOption {
+	return func(client *Client) error {
+		if retries < 0 {
+			return fmt.Errorf("retries %d must not be negative", retries)
+		}
+		client.retries = retries
+		client.retryWait = wait
+		return nil
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(client *Client) error {
+		client.httpClient = httpClient
+		return nil
+	}
+}
+
+// Fetch requests the environment for the application, profile and optional label.
+func (c *Client) Fetch(ctx context.Context, application, profile, label string) (*Environment, error) {
+	if application == "" {
+		return nil, errors.New("application name is required")
+	}
+	if profile == "" {
+		profile = defaultProfile
+	}
+	endpoint := c.endpoint(application, profile, label)
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var (
+			env       *Environment
+			retryable bool
+		)
+		env, retryable, err = c.fetch(ctx, endpoint)
+		if err == nil {
+			return env, nil
+		}
+		if !retryable || attempt >= c.retries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.retryWait):
+		}
+	}
+	return nil, err
+}
+
+func (c *Client) endpoint(application, profile, label string) string {
+	u := *c.uri
+	segments := []string{application, profile}
+	if label != "" {
+		// Config Server expects slashes in labels to be escaped as (_)
+		segments = append(segments, strings.ReplaceAll(label, "/", "(_)"))
+	}
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		// parentheses are valid in a path, keep them as Spring clients do
+		escaped[i] = strings.NewReplacer("%28", "(", "%29", ")").Replace(url.PathEscape(segment))
+	}
+	u.RawPath = strings.TrimSuffix(u.EscapedPath(), "/") + "/" + strings.Join(escaped, "/")
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.Join(segments, "/")
+	return u.String()
+}
+
+func (c *Client) fetch(ctx context.Context, endpoint string) (*Environment, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "create request error")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	} else if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, errors.Wrap(err, "config server request error")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return nil, retryable, fmt.Errorf("config server responded with status %s", resp.Status)
+	}
+	env := &Environment{}
+	if err = json.NewDecoder(resp.Body).Decode(env); err != nil {
+		return nil, false, errors.Wrap(err, "config server response decoding error")
+	}
+	return env, false, nil
+}
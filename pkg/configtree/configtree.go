@@ -0,0 +1,187 @@
+// Package configtree writes properties as a directory with one file per property, as read by Spring Boot's
+// spring.config.import=configtree:... and used by Docker and Kubernetes secrets.
+package configtree
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+	"github.com/pkg/errors"
+)
+
+// Naming is the way property paths are mapped to file names.
+type Naming string
+
+const (
+	// NamingDotted names the files by the property path e.g. spring.datasource.password
+	NamingDotted Naming = "dotted"
+	// NamingNested creates a directory per path segment e.g. spring/datasource/password
+	NamingNested Naming = "nested"
+	// NamingEnv names the files like environment variables e.g. SPRING_DATASOURCE_PASSWORD
+	NamingEnv Naming = "env"
+)
+
+// Namings are the supported namings.
+var Namings = []Naming{NamingDotted, NamingNested, NamingEnv}
+
+// DefaultPerm is the permission of the written files, readable by the owner only.
+const DefaultPerm os.FileMode = 0400
+
+// ParseNaming parses the naming name.
+func ParseNaming(value string) (Naming, error) {
+	for _, naming := range Namings {
+		if string(naming) == value {
+			return naming, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported naming '%s', expected one of %v", value, Namings)
+}
+
+type WriterOption func(*Writer) error
+
+// Writer writes the config trees.
+type Writer struct {
+	naming Naming
+	perm   os.FileMode
+}
+
+// NewWriter creates a writer with dotted naming and the default permission.
+func NewWriter(options ...WriterOption) (*Writer, error) {
+	writer := &Writer{naming: NamingDotted, perm: DefaultPerm}
+	for _, option := range options {
+		if err := option(writer); err != nil {
+			return nil, err
+		}
+	}
+	return writer, nil
+}
+
+func WithNaming(naming Naming) WriterOption {
+	return func(writer *Writer) error {
+		if _, err := ParseNaming(string(naming)); err != nil {
+			return err
+		}
+		writer.naming = naming
+		return nil
+	}
+}
+
+func WithPerm(perm os.FileMode) WriterOption {
+	return func(writer *Writer) error {
+		writer.perm = perm
+		return nil
+	}
+}
+
+// FileName returns the slash separated file name of the property path.
+func (w *Writer) FileName(path string) (string, error) {
+	var name string
+	switch w.naming {
+	case NamingEnv:
+		name = properties.EnvName(path)
+	case NamingNested:
+		segments, err := properties.ParsePath(path)
+		if err != nil {
+			return "", err
+		}
+		parts := make([]string, len(segments))
+		for i, segment := range segments {
+			if segment.IsIndex() {
+				parts[i] = strconv.Itoa(segment.Index)
+			} else if parts[i] = segment.Key; strings.Contains(parts[i], "/") {
+				return "", fmt.Errorf("property '%s' cannot be a file name", path)
+			}
+		}
+		name = strings.Join(parts, "/")
+	default:
+		if name = path; strings.Contains(name, "/") {
+			return "", fmt.Errorf("property '%s' cannot be a file name", path)
+		}
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == "" || part == "." || part == ".." || strings.ContainsRune(part, '\\') {
+			return "", fmt.Errorf("property '%s' cannot be a file name", path)
+		}
+	}
+	return name, nil
+}
+
+// Write writes the properties into a new directory next to the target and then atomically replaces the target
+// by a symlink to it, so readers never see a partially written tree. The directory of the previous write is removed.
+// The target must not exist or be a symlink.
+func (w *Writer) Write(target string, props properties.Properties) (err error) {
+	target = filepath.Clean(target)
+	parent, base := filepath.Dir(target), filepath.Base(target)
+	prefix := "." + base + "_"
+	previous := ""
+	info, err := os.Lstat(target)
+	switch {
+	case err == nil && info.Mode()&os.ModeSymlink == 0:
+		return errors.Errorf("%s exists and is not a symlink", target)
+	case err == nil:
+		if previous, err = os.Readlink(target); err != nil {
+			return errors.Wrap(err, "read symlink error")
+		}
+	case !os.IsNotExist(err):
+		return errors.Wrap(err, "stat error")
+	}
+
+	dataDir, err := ioutil.TempDir(parent, prefix)
+	if err != nil {
+		return errors.Wrap(err, "create directory error")
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(dataDir)
+		}
+	}()
+	for _, key := range props.Keys() {
+		if err = w.writeFile(dataDir, key, properties.ToString(props[key])); err != nil {
+			return err
+		}
+	}
+
+	link := dataDir + ".link"
+	if err = os.Symlink(filepath.Base(dataDir), link); err != nil {
+		return errors.Wrap(err, "create symlink error")
+	}
+	if err = os.Rename(link, target); err != nil {
+		_ = os.Remove(link)
+		return errors.Wrap(err, "replace symlink error")
+	}
+	// only directories of earlier writes are removed
+	if previous != "" && !filepath.IsAbs(previous) && filepath.Dir(previous) == "." && strings.HasPrefix(previous, prefix) {
+		if err := os.RemoveAll(filepath.Join(parent, previous)); err != nil {
+			return errors.Wrap(err, "remove previous directory error")
+		}
+	}
+	return nil
+}
+
+func (w *Writer) writeFile(dir, key, value string) error {
+	name, err := w.FileName(key)
+	if err != nil {
+		return err
+	}
+	fileName := filepath.Join(dir, filepath.FromSlash(name))
+	if err = os.MkdirAll(filepath.Dir(fileName), 0700); err != nil {
+		return errors.Wrapf(err, "property '%s'", key)
+	}
+	f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, w.perm)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("property '%s': file %s is written by another property", key, name)
+		}
+		return errors.Wrapf(err, "property '%s'", key)
+	}
+	if _, err = f.WriteString(value); err != nil {
+		_ = f.Close()
+		return errors.Wrapf(err, "property '%s'", key)
+	}
+	return errors.Wrapf(f.Close(), "property '%s'", key)
+}
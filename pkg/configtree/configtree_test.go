@@ -0,0 +1,124 @@
+package configtree
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+)
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		naming   Naming
+		path     string
+		expected string
+		err      string
+	}{
+		{naming: NamingDotted, path: "spring.datasource.password", expected: "spring.datasource.password"},
+		{naming: NamingDotted, path: "servers[0].host", expected: "servers[0].host"},
+		{naming: NamingNested, path: "servers[0].host", expected: "servers/0/host"},
+		{naming: NamingNested, path: "map[a.b].c", expected: "map/a.b/c"},
+		{naming: NamingEnv, path: "spring.data-source.password", expected: "SPRING_DATASOURCE_PASSWORD"},
+		{naming: NamingDotted, path: "a/b", err: "property 'a/b' cannot be a file name"},
+		{naming: NamingNested, path: "map[..].b", err: "property 'map[..].b' cannot be a file name"},
+		{naming: NamingDotted, path: "..", err: "property '..' cannot be a file name"},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.naming)+" "+tc.path, func(t *testing.T) {
+			writer, err := NewWriter(WithNaming(tc.naming))
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := writer.FileName(tc.path)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected error %q, actual %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil || actual != tc.expected {
+				t.Errorf("Expected %q, actual %q %v", tc.expected, actual, err)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configtree")
+	if err != nil {
+		t.Fatalf("temp dir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, "secrets")
+
+	writer, err := NewWriter(WithNaming(NamingNested))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = writer.Write(target, properties.Properties{"db.password": "first", "db.user": "app", "port": 8080}); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	first, err := os.Readlink(target)
+	if err != nil {
+		t.Fatalf("read link error: %v", err)
+	}
+	assertFile(t, filepath.Join(target, "db", "password"), "first")
+	assertFile(t, filepath.Join(target, "port"), "8080")
+
+	if err = writer.Write(target, properties.Properties{"db.password": "second"}); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	assertFile(t, filepath.Join(target, "db", "password"), "second")
+	if _, err = os.Stat(filepath.Join(target, "db", "user")); !os.IsNotExist(err) {
+		t.Errorf("Expected removed property, actual %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, first)); !os.IsNotExist(err) {
+		t.Errorf("Expected removed previous directory, actual %v", err)
+	}
+
+	// a failed write keeps the previous tree
+	err = writer.Write(target, properties.Properties{"a": "x", "a.b": "y"})
+	if err == nil || !strings.HasPrefix(err.Error(), "property 'a.b': ") {
+		t.Errorf("Unexpected error %v", err)
+	}
+	assertFile(t, filepath.Join(target, "db", "password"), "second")
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("Expected the symlink and its directory, actual %d %v", len(entries), err)
+	}
+
+	env, _ := NewWriter(WithNaming(NamingEnv))
+	err = env.Write(target, properties.Properties{"a.b": "x", "a[b]": "y"})
+	if err == nil || err.Error() != "property 'a[b]': file A_B is written by another property" {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	plain := filepath.Join(dir, "plain")
+	if err = os.Mkdir(plain, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err = writer.Write(plain, properties.Properties{"a": "x"}); err == nil || err.Error() != plain+" exists and is not a symlink" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func assertFile(t *testing.T, fileName, expected string) {
+	t.Helper()
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("read file error: %v", err)
+	}
+	if string(content) != expected {
+		t.Errorf("%s: expected %q, actual %q", fileName, expected, content)
+	}
+	info, err := os.Stat(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != DefaultPerm {
+		t.Errorf("%s: expected permission %v, actual %v", fileName, DefaultPerm, info.Mode().Perm())
+	}
+}
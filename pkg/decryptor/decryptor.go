@@ -14,6 +14,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	merror "github.com/grepplabs/spring-config-decryptor/pkg/errors"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
 	"io"
 	"regexp"
 	"strings"
@@ -23,19 +24,46 @@ import (
 )
 
 const (
-	cipherPrefix = "{cipher}"
-	defaultSalt  = "deadbeef"
+	// CipherPrefix marks the encrypted values.
+	CipherPrefix = "{cipher}"
+	// DefaultSalt is the hex encoded salt used by Spring when none is configured.
+	DefaultSalt = "deadbeef"
 )
 
+// RsaAlgorithm is the RSA padding used to encrypt the session key, as in Spring's RsaAlgorithm.
+type RsaAlgorithm string
+
+const (
+	// RsaAlgorithmDefault is RSA/ECB/PKCS1Padding.
+	RsaAlgorithmDefault RsaAlgorithm = "DEFAULT"
+	// RsaAlgorithmOAEP is RSA/ECB/OAEPPadding with SHA-1.
+	RsaAlgorithmOAEP RsaAlgorithm = "OAEP"
+)
+
+func ParseRsaAlgorithm(value string) (RsaAlgorithm, error) {
+	switch algorithm := RsaAlgorithm(strings.ToUpper(value)); algorithm {
+	case RsaAlgorithmDefault, RsaAlgorithmOAEP:
+		return algorithm, nil
+	}
+	return "", fmt.Errorf("unknown RSA algorithm '%s', expected DEFAULT or OAEP", value)
+}
+
 var (
-	cipherPattern = regexp.MustCompile(`{cipher}([A-Za-z0-9+/=]*)`)
+	cipherPattern = regexp.MustCompile(`{cipher}((?:{[a-z]+:[^{}]*})*[A-Za-z0-9+/=]*)`)
+	prefixPattern = regexp.MustCompile(`^{([a-z]+):([^{}]*)}`)
 )
 
 type ValueDecryptorOption func(decryptor *ValueDecryptor) error
 
 type ValueDecryptor struct {
-	privateKey *rsa.PrivateKey
-	salt       []byte
+	privateKey  *rsa.PrivateKey
+	fingerprint string
+	keyring     []keyringEntry
+	cache       *keyCache
+	salt        []byte
+	keyAlias    string
+	algorithm   RsaAlgorithm
+	strong      bool
 }
 
 func NewValueDecryptor(key []byte, options ...ValueDecryptorOption) (*ValueDecryptor, error) {
@@ -43,8 +71,12 @@ func NewValueDecryptor(key []byte, options ...ValueDecryptorOption) (*ValueDecry
 	if err != nil {
 		return nil, err
 	}
-	result := &ValueDecryptor{privateKey: privateKey}
-	if err := WithSalt(defaultSalt)(result); err != nil {
+	fingerprint, err := Fingerprint(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	result := &ValueDecryptor{privateKey: privateKey, fingerprint: fingerprint, algorithm: RsaAlgorithmDefault}
+	if err := WithSalt(DefaultSalt)(result); err != nil {
 		return nil, err
 	}
 	for _, option := range options {
@@ -85,40 +117,177 @@ func WithSalt(salt string) ValueDecryptorOption {
 	}
 }
 
+// WithKeyAlias sets the alias of the private key, values with a different {key:alias} prefix are rejected.
+func WithKeyAlias(alias string) ValueDecryptorOption {
+	return func(decryptor *ValueDecryptor) error {
+		decryptor.keyAlias = alias
+		return nil
+	}
+}
+
+// WithAlgorithm sets the RSA padding of the session key.
+func WithAlgorithm(algorithm RsaAlgorithm) ValueDecryptorOption {
+	return func(decryptor *ValueDecryptor) error {
+		decryptor.algorithm = algorithm
+		return nil
+	}
+}
+
+// WithStrong enables the AES/GCM encryption of the value used by Spring's strong mode instead of AES/CBC.
+func WithStrong(strong bool) ValueDecryptorOption {
+	return func(decryptor *ValueDecryptor) error {
+		decryptor.strong = strong
+		return nil
+	}
+}
+
+// PublicKey returns the public key of the private key.
+func (d ValueDecryptor) PublicKey() *rsa.PublicKey {
+	return &d.privateKey.PublicKey
+}
+
 func (d ValueDecryptor) DecryptValue(value string) (string, error) {
-	if !strings.HasPrefix(value, cipherPrefix) {
+	if !strings.HasPrefix(value, CipherPrefix) {
 		return value, nil
 	}
-	value = strings.TrimPrefix(value, cipherPrefix)
+	plainText, err := d.DecryptValueBytes(value)
+	if err != nil {
+		return "", err
+	}
+	defer Zero(plainText)
+	return string(plainText), nil
+}
+
+// DecryptValueBytes decrypts the {cipher} value into a buffer owned by the caller,
+// which should be cleared with Zero as soon as the plain text is not needed.
+func (d ValueDecryptor) DecryptValueBytes(value string) ([]byte, error) {
+	plainText, _, err := d.DecryptValueWithKey(value)
+	return plainText, err
+}
+
+// DecryptValueWithKey is DecryptValueBytes which also returns the fingerprint of the key which decrypted the value,
+// empty if the value is not encrypted.
+func (d ValueDecryptor) DecryptValueWithKey(value string) ([]byte, string, error) {
+	if !strings.HasPrefix(value, CipherPrefix) {
+		return []byte(value), "", nil
+	}
+	value = strings.TrimPrefix(value, CipherPrefix)
+	value, prefixes := splitPrefixes(value)
+	if alias, ok := prefixes["key"]; ok && d.keyAlias != "" && alias != d.keyAlias {
+		return nil, "", fmt.Errorf("%w '%s'", ErrUnknownKeyAlias, alias)
+	}
 	data, err := base64.StdEncoding.DecodeString(value)
 	if err != nil {
-		return "", fmt.Errorf("value '%s' cannot be base64 decoded: %v", value, err)
+		// do not include the value, the error messages may end up in logs
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidBase64, err)
 	}
-	return d.decryptData(data)
+	if len(d.keyring) != 0 {
+		return d.decryptWithKeyring(data)
+	}
+	plainText, err := d.decryptData(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return plainText, d.fingerprint, nil
 }
 
-func (d ValueDecryptor) decryptData(data []byte) (string, error) {
-	if len(data) < 2 {
-		return "", errors.New("data too short to read session key length")
+// Zero overwrites the buffer with zeros.
+func Zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// keyAlias returns the alias from the {key:alias} prefix of the {cipher} value.
+func keyAlias(value string) string {
+	_, prefixes := splitPrefixes(strings.TrimPrefix(value, CipherPrefix))
+	return prefixes["key"]
+}
+
+// splitPrefixes removes the {name:value} prefixes like {key:alias} from the value.
+func splitPrefixes(value string) (string, map[string]string) {
+	prefixes := make(map[string]string)
+	for {
+		m := prefixPattern.FindStringSubmatch(value)
+		if m == nil {
+			return value, prefixes
+		}
+		prefixes[m[1]] = m[2]
+		value = value[len(m[0]):]
 	}
-	length := binary.BigEndian.Uint16(data[0:2])
+}
 
-	if len(data) < int(length+2) {
-		return "", errors.New("data too short to read session key cipher text")
+func (d ValueDecryptor) decryptData(data []byte) ([]byte, error) {
+	key, payload, err := d.unwrapKey(data)
+	if err != nil {
+		return nil, err
+	}
+	defer Zero(key)
+	return d.decryptPayload(key, payload)
+}
+
+// unwrapKey decrypts the session key with the private key and derives the AES key from it.
+// The remaining AES payload is returned as well.
+func (d ValueDecryptor) unwrapKey(data []byte) (key []byte, payload []byte, err error) {
+	return d.unwrapKeyWith(d.privateKey, data)
+}
+
+// unwrapKeyWith is unwrapKey with another private key, the payload is returned also if the RSA decryption fails.
+func (d ValueDecryptor) unwrapKeyWith(privateKey *rsa.PrivateKey, data []byte) (key []byte, payload []byte, err error) {
+	length, err := sessionKeyLength(data)
+	if err != nil {
+		return nil, nil, err
 	}
 	ciphertext := data[2 : length+2]
 
-	iv, err := rsa.DecryptPKCS1v15(rand.Reader, d.privateKey, ciphertext)
+	var iv []byte
+	if d.algorithm == RsaAlgorithmOAEP {
+		iv, err = rsa.DecryptOAEP(sha1.New(), rand.Reader, privateKey, ciphertext, nil)
+	} else {
+		iv, err = rsa.DecryptPKCS1v15(rand.Reader, privateKey, ciphertext)
+	}
 	if err != nil {
-		return "", err
+		return nil, data[2+length:], ErrRSADecryption
 	}
-	key := pbkdf2.Key([]byte(hex.EncodeToString(iv)), d.salt, 1024, 32, sha1.New)
+	key = deriveKey(iv, d.salt)
+	Zero(iv)
+	return key, data[2+length:], nil
+}
 
-	plaintext, err := d.decryptCBC(key, data[2+length:])
+// sessionKeyLength returns the length of the RSA encrypted session key and checks the data contains it.
+func sessionKeyLength(data []byte) (int, error) {
+	if len(data) < 2 {
+		return 0, fmt.Errorf("%w to read session key length", ErrDataTooShort)
+	}
+	length := int(binary.BigEndian.Uint16(data[0:2]))
+	if len(data) < length+2 {
+		return 0, fmt.Errorf("%w to read session key cipher text", ErrDataTooShort)
+	}
+	return length, nil
+}
+
+// deriveKey derives the AES key from the hex encoded session secret as Spring does.
+func deriveKey(secret []byte, salt []byte) []byte {
+	password := []byte(hex.EncodeToString(secret))
+	defer Zero(password)
+	return pbkdf2.Key(password, salt, 1024, 32, sha1.New)
+}
+
+// decryptPayload decrypts the AES payload with the derived key.
+func (d ValueDecryptor) decryptPayload(key, payload []byte) ([]byte, error) {
+	if d.strong {
+		return d.decryptGCM(key, payload)
+	}
+	plaintext, err := d.decryptCBC(key, payload)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	unpadded, err := d.unpad(plaintext)
+	if err != nil {
+		Zero(plaintext)
+		return nil, err
 	}
-	return string(d.unpad(plaintext)), nil
+	return unpadded, nil
 }
 
 func (d ValueDecryptor) decryptCBC(key, ciphertext []byte) (plaintext []byte, err error) {
@@ -128,11 +297,14 @@ func (d ValueDecryptor) decryptCBC(key, ciphertext []byte) (plaintext []byte, er
 		return
 	}
 	if len(ciphertext) < aes.BlockSize {
-		return nil, errors.New("cipher text length shorter than AES block size")
+		return nil, fmt.Errorf("%w to read AES initialization vector", ErrDataTooShort)
 	}
 
 	iv := ciphertext[:aes.BlockSize]
 	ciphertext = ciphertext[aes.BlockSize:]
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: cipher text length is not a multiple of AES block size", ErrInvalidPadding)
+	}
 
 	cbc := cipher.NewCBCDecrypter(block, iv)
 	cbc.CryptBlocks(ciphertext, ciphertext)
@@ -142,27 +314,122 @@ func (d ValueDecryptor) decryptCBC(key, ciphertext []byte) (plaintext []byte, er
 	return
 }
 
-func (d ValueDecryptor) unpad(src []byte) []byte {
+func (d ValueDecryptor) decryptGCM(key, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aes.BlockSize+gcm.Overhead() {
+		return nil, fmt.Errorf("%w to read AES/GCM nonce and tag", ErrDataTooShort)
+	}
+	plaintext, err := gcm.Open(nil, ciphertext[:aes.BlockSize], ciphertext[aes.BlockSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPadding, err)
+	}
+	return plaintext, nil
+}
+
+func (d ValueDecryptor) unpad(src []byte) ([]byte, error) {
 	length := len(src)
 	unpadding := int(src[length-1])
-	return src[:(length - unpadding)]
+	if unpadding == 0 || unpadding > aes.BlockSize || unpadding > length {
+		return nil, ErrInvalidPadding
+	}
+	for _, b := range src[length-unpadding:] {
+		if int(b) != unpadding {
+			return nil, ErrInvalidPadding
+		}
+	}
+	return src[:(length - unpadding)], nil
 }
 
+type ConfigDecryptorOption func(decryptor *ConfigDecryptor)
+
 type ConfigDecryptor struct {
-	valueDecryptor *ValueDecryptor
+	schemes     []Scheme
+	errorPolicy ErrorPolicy
+	placeholder string
+	fileName    string
+	redactor    Redactor
 }
 
-func NewConfigDecryptor(valueDecryptor *ValueDecryptor) *ConfigDecryptor {
-	return &ConfigDecryptor{
-		valueDecryptor: valueDecryptor,
+// NewConfigDecryptor decrypts the {cipher} values with the value decryptor and the values of the schemes added by options.
+func NewConfigDecryptor(valueDecryptor CipherDecryptor, options ...ConfigDecryptorOption) *ConfigDecryptor {
+	return NewSchemeDecryptor([]Scheme{NewCipherScheme(valueDecryptor)}, options...)
+}
+
+// NewSchemeDecryptor decrypts the values of the schemes, which are tried in the given order.
+func NewSchemeDecryptor(schemes []Scheme, options ...ConfigDecryptorOption) *ConfigDecryptor {
+	result := &ConfigDecryptor{
+		schemes:     schemes,
+		errorPolicy: FailFast,
+		placeholder: defaultPlaceholder,
 	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
 }
 
-func (c ConfigDecryptor) Decrypt(output io.Writer, input io.Reader) (err error) {
+// WithScheme adds a scheme of encrypted values, e.g. one created by NewScheme.
+func WithScheme(scheme Scheme) ConfigDecryptorOption {
+	return func(decryptor *ConfigDecryptor) {
+		decryptor.schemes = append(decryptor.schemes, scheme)
+	}
+}
+
+// WithMarker decrypts the values wrapped in prefix and suffix, like Jasypt's ENC(...), with the value decryptor.
+func WithMarker(prefix, suffix string, valueDecryptor CipherDecryptor) ConfigDecryptorOption {
+	return WithScheme(NewMarkerScheme(prefix, suffix, valueDecryptor))
+}
+
+// findValue returns the position of the first encrypted value of any scheme in the text and its scheme.
+// Of schemes matching at the same position the first one wins.
+func (c ConfigDecryptor) findValue(text string) ([]int, Scheme) {
 	var (
-		line string
+		result []int
+		scheme Scheme
 	)
-	rd := bufio.NewReader(input)
+	for _, s := range c.schemes {
+		if ns := s.Match(text); ns != nil && (result == nil || ns[0] < result[0]) {
+			result, scheme = ns, s
+		}
+	}
+	return result, scheme
+}
+
+func WithErrorPolicy(policy ErrorPolicy) ConfigDecryptorOption {
+	return func(decryptor *ConfigDecryptor) {
+		decryptor.errorPolicy = policy
+	}
+}
+
+func WithPlaceholder(placeholder string) ConfigDecryptorOption {
+	return func(decryptor *ConfigDecryptor) {
+		decryptor.placeholder = placeholder
+	}
+}
+
+// WithFileName sets the file name reported in decryption errors.
+func WithFileName(fileName string) ConfigDecryptorOption {
+	return func(decryptor *ConfigDecryptor) {
+		decryptor.fileName = fileName
+	}
+}
+
+func (c ConfigDecryptor) Decrypt(output io.Writer, input io.Reader) (err error) {
+	_, err = c.DecryptWithReport(output, input)
+	return err
+}
+
+// DecryptWithReport decrypts the input and returns the report of values which could not be decrypted.
+func (c ConfigDecryptor) DecryptWithReport(output io.Writer, input io.Reader) (report *Report, err error) {
+	report = &Report{}
+	paths := properties.NewPathTracker()
 	wr := bufio.NewWriter(output)
 	defer func() {
 		merr := merror.MultiError{}
@@ -170,27 +437,64 @@ func (c ConfigDecryptor) Decrypt(output io.Writer, input io.Reader) (err error)
 		merr.Add(errors.Wrap(wr.Flush(), "writing flush error"))
 		err = merr.Err()
 	}()
-	for {
-		if line, err = rd.ReadString('\n'); err != nil {
-			if err == io.EOF {
-				if err = c.decryptLine(wr, line); err != nil {
-					return err
-				}
-				break
+	err = readLines(input, func(line string, lineNo int) error {
+		return c.decryptLine(wr, line, lineNo, paths, report)
+	})
+	return report, err
+}
+
+// Check verifies that every {cipher} value of the input can be decrypted.
+// The plain text is cleared right after each value is decrypted and never written anywhere.
+func (c ConfigDecryptor) Check(input io.Reader) (*Report, error) {
+	report := &Report{}
+	paths := properties.NewPathTracker()
+	err := readLines(input, func(line string, lineNo int) error {
+		path := paths.Next(line).Path
+		for offset := 0; ; {
+			ns, scheme := c.findValue(line[offset:])
+			if ns == nil {
+				return nil
 			}
+			value := line[offset+ns[0] : offset+ns[1]]
+			report.Values++
+			plainText, fingerprint, err := scheme.Decrypt(value)
+			Zero(plainText)
+			if err != nil {
+				report.add(c.newDecryptError(lineNo, offset+ns[0]+1, path, value, err))
+			} else {
+				report.addKey(fingerprint)
+			}
+			offset += ns[1]
+		}
+	})
+	return report, err
+}
+
+func readLines(input io.Reader, fn func(line string, lineNo int) error) error {
+	rd := bufio.NewReader(input)
+	for lineNo := 1; ; lineNo++ {
+		line, err := rd.ReadString('\n')
+		if err != nil && err != io.EOF {
 			return fmt.Errorf("read file line error: %v", err)
 		}
-		if err = c.decryptLine(wr, line); err != nil {
+		eof := err == io.EOF
+		if err = fn(line, lineNo); err != nil {
 			return err
 		}
+		if eof {
+			return nil
+		}
 	}
-	return nil
 }
 
-func (c ConfigDecryptor) decryptLine(wr *bufio.Writer, line string) (err error) {
-	line, err = c.processLine(line)
+func (c ConfigDecryptor) newDecryptError(lineNo, column int, path, value string, err error) *DecryptError {
+	return &DecryptError{File: c.fileName, Line: lineNo, Column: column, Path: path, KeyAlias: keyAlias(value), Err: err}
+}
+
+func (c ConfigDecryptor) decryptLine(wr *bufio.Writer, line string, lineNo int, paths *properties.PathTracker, report *Report) (err error) {
+	line, err = c.processLine(line, lineNo, paths, report)
 	if err != nil {
-		return fmt.Errorf("line processing error: %v", err)
+		return err
 	}
 	_, err = wr.WriteString(line)
 	if err != nil {
@@ -199,27 +503,118 @@ func (c ConfigDecryptor) decryptLine(wr *bufio.Writer, line string) (err error)
 	return nil
 }
 
-func (c ConfigDecryptor) processLine(line string) (string, error) {
-	var sb strings.Builder
+func (c ConfigDecryptor) processLine(line string, lineNo int, paths *properties.PathTracker, report *Report) (string, error) {
+	var (
+		sb     strings.Builder
+		offset int
+		drop   bool
+	)
+	path := paths.Next(line).Path
+	rest := line
+	for {
+		ns, scheme := c.findValue(rest)
+		if ns == nil {
+			sb.WriteString(rest)
+			break
+		}
+		sb.WriteString(rest[:ns[0]])
+		value := rest[ns[0]:ns[1]]
+		report.Values++
+		plainText, err := c.decryptValue(scheme, value, report)
+		if err != nil {
+			decryptErr := c.newDecryptError(lineNo, offset+ns[0]+1, path, value, err)
+			report.add(decryptErr)
+			switch c.errorPolicy {
+			case KeepCipherText:
+				plainText = value
+			case ReplaceWithPlaceholder:
+				plainText = c.placeholder
+			case DropLine:
+				drop = true
+			default:
+				return "", decryptErr
+			}
+		}
+		sb.WriteString(plainText)
+		offset += ns[1]
+		rest = rest[ns[1]:]
+	}
+	if drop {
+		return "", nil
+	}
+	return sb.String(), nil
+}
 
+// DecryptValue decrypts a property value which is a single encrypted value of any scheme as Spring does,
+// other values are returned unchanged.
+func (c ConfigDecryptor) DecryptValue(value string) (string, error) {
+	ns, scheme := c.findValue(value)
+	if ns == nil || ns[0] != 0 || ns[1] != len(value) {
+		return value, nil
+	}
+	return c.decryptValue(scheme, value, &Report{})
+}
+
+// DecryptText replaces each encrypted value of any scheme in the text with its plain text.
+// The rest of the text is left byte-for-byte unchanged.
+func (c ConfigDecryptor) DecryptText(text string) (string, error) {
+	var sb strings.Builder
 	for {
-		ns := cipherPattern.FindStringIndex(line)
+		ns, scheme := c.findValue(text)
 		if ns == nil {
-			sb.WriteString(line)
 			break
 		}
-		sb.WriteString(line[:ns[0]])
-		value := line[ns[0]:ns[1]]
-		plainText, err := c.valueDecryptor.DecryptValue(value)
+		plainText, err := c.decryptValue(scheme, text[ns[0]:ns[1]], &Report{})
 		if err != nil {
 			return "", err
 		}
+		sb.WriteString(text[:ns[0]])
 		sb.WriteString(plainText)
-		line = line[ns[1]:]
+		text = text[ns[1]:]
+	}
+	sb.WriteString(text)
+	return sb.String(), nil
+}
+
+func (c ConfigDecryptor) decryptValue(scheme Scheme, value string, report *Report) (string, error) {
+	plainText, fingerprint, err := scheme.Decrypt(value)
+	defer Zero(plainText)
+	if err != nil {
+		return "", err
+	}
+	report.addKey(fingerprint)
+	if c.redactor == nil {
+		return string(plainText), nil
 	}
+	return c.redactor(plainText), nil
+}
+
+// ReplaceCipherValues substitutes each {cipher} value of the text with the result of replace.
+// The rest of the text is left byte-for-byte unchanged.
+func ReplaceCipherValues(text string, replace func(value string) (string, error)) (string, error) {
+	var (
+		sb   strings.Builder
+		last int
+	)
+	for _, ns := range cipherPattern.FindAllStringIndex(text, -1) {
+		replacement, err := replace(text[ns[0]:ns[1]])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(text[last:ns[0]])
+		sb.WriteString(replacement)
+		last = ns[1]
+	}
+	sb.WriteString(text[last:])
 	return sb.String(), nil
 }
 
+// IsCipherValue reports whether the whole value is a single {cipher} value.
+func IsCipherValue(value string) bool {
+	loc := cipherPattern.FindStringIndex(value)
+	return loc != nil && loc[0] == 0 && loc[1] == len(value)
+}
+
 type Decryptor interface {
 	Decrypt(output io.Writer, input io.Reader) (err error)
 }
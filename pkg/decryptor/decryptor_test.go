@@ -2,7 +2,13 @@ package decryptor
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"errors"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -74,7 +80,7 @@ func TestDecryptValue(t *testing.T) {
 		{name: "Decrypt error illegal base64 data",
 			value:    "{cipher}hello world",
 			expected: "",
-			err:      errors.New("value 'hello world' cannot be base64 decoded: illegal base64 data at input byte 5")},
+			err:      errors.New("value cannot be base64 decoded: illegal base64 data at input byte 5")},
 		{name: "Decrypt 1",
 			value:    "{cipher}AQC7ZYjq/MOw4M6CnPMOqH6flPQC5UvjIT/wBol/8b24M+jiH6WwLmdg5if412xmkA7w17Zh2AuL04S0QM5M8hfEy0XBlsMfomIrDrgzDkmT4z4meAYHIKcTENYstFgt34bTHgUBofLp/czR3NP4TQjFnmxGx6XyhDyxr+S4c81RYIngC8mY54KHu9ctQzq0utqhUG9o1XWyvUKN+b1m4GSlebFZQtHAcmrdXgLWl5hw/JrcouoXhM3w13tyvkzyrgF0nKbZlxKn/9ummSLURIjLL2DRrfxmr9jwsDnJR10jkbTEmMR5b+wwWl4nas/2XK4lwFO6PuPEi5b23OLaHeJzrnD0Rndp4v4A3Bxup+4JFQJzRB2MqSrMyhmTzDLm1oU=",
 			expected: "1"},
@@ -198,3 +204,306 @@ data:
 		}
 	}
 }
+
+func TestDecryptConfigErrorPolicy(t *testing.T) {
+	const (
+		cipherFoo   = "{cipher}AQCE7t4KSgXRgRGRkJr4KhcS8Y5YsWzU07ac67ECLJPu6IbxkrkLn3mRl/FaTumJrbjX6+0gkG8e/TARjCj4tsVqx9Y8KK5yISaBHArKjyXDAJ71+nSsJAX/tcukONFGBqxYBkXH9OcXH8hoNagWWg/4pt3CwGw/wGgFU3dBLdvf8gu7S8YxCHWE5TSkUvxB/Gs/C5JLkklE3vz3ATYCnDTx1X8weQUxKeqOqe8AaElq8QkpVeJackkzsv2w6A8YydterEuELSjk5icLF0CKHlpD9x+emiprmaOADxjP526YinTlGnRsiDroaZ3avIURjUc+GCOt47i8grQIT1DmzUvailAMfsVgvnsSyKOO18VSqe11l9AKMnzEwqJ8cmHT3Kc="
+		cipherWrong = "{cipher}AQCE8/DTlSRAmt7KXjWe7FSlxD+e3Gv7pcq469QyYzhPuNmgOlzOZbze3S36e0Wzdqwzk/YBTd0GtywC56TCypAz6/LE/aUxz4WMPJkKpx3xKeiR1h7qr9embtt4ssixSeVkTbSdypGTEgJMMU65dYHjyypGipXD8JiebnysnwSYQSdbXKXxXq/U/+Z6r3mvk7yBKsDi4TAm99AzCMnBcwsDB2OnKTQSNWaq70w6T/XtzP78sDaBl73wMTRLjjh5jZ8gNH7ozG+oJ8jhwy6n+1D/3cO5uPhiDJi8XormYS6ydMEscx++lQDSBUPy0ukmM6l8horhyP456p61lYkrfiaHX58C/A2wraQ2nWLJY7mNWia6kR4Rn+HNi41FDIFw2Jc="
+	)
+	valueDecryptor, err := NewValueDecryptor([]byte(privateKey))
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	input := "db:\n  user: '" + cipherFoo + "'\n  password: '" + cipherWrong + "'\n  port: 5432\n"
+	tt := []struct {
+		name   string
+		policy ErrorPolicy
+
+		expected string
+		err      bool
+	}{
+		{name: "Fail fast",
+			policy:   FailFast,
+			expected: "db:\n  user: 'foo'\n",
+			err:      true},
+		{name: "Keep cipher text",
+			policy:   KeepCipherText,
+			expected: "db:\n  user: 'foo'\n  password: '" + cipherWrong + "'\n  port: 5432\n"},
+		{name: "Replace with placeholder",
+			policy:   ReplaceWithPlaceholder,
+			expected: "db:\n  user: 'foo'\n  password: '<n/a>'\n  port: 5432\n"},
+		{name: "Drop line",
+			policy:   DropLine,
+			expected: "db:\n  user: 'foo'\n  port: 5432\n"},
+	}
+	for _, tc := range tt {
+		buf := new(bytes.Buffer)
+		report, err := NewConfigDecryptor(valueDecryptor, WithErrorPolicy(tc.policy)).DecryptWithReport(buf, strings.NewReader(input))
+		if actual := buf.String(); actual != tc.expected {
+			t.Errorf("%s: values differ: expected %v, actual %v", tc.name, tc.expected, actual)
+		}
+		if (err != nil) != tc.err {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if len(report.Failures) != 1 {
+			t.Fatalf("%s: expected 1 failure, actual %v", tc.name, report.Failures)
+		}
+		if !errors.Is(report.Err(), ErrRSADecryption) {
+			t.Errorf("%s: expected RSA decryption cause, actual %v", tc.name, report.Err())
+		}
+		failure := report.Failures[0]
+		if failure.Line != 3 || failure.Column != 14 || failure.Path != "db.password" {
+			t.Errorf("%s: unexpected failure position %v", tc.name, failure)
+		}
+	}
+}
+
+func TestDecryptErrorCauses(t *testing.T) {
+	valueDecryptor, err := NewValueDecryptor([]byte(privateKey), WithKeyAlias("primary"))
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	tt := []struct {
+		name  string
+		value string
+
+		cause error
+	}{
+		{name: "Invalid base64", value: "{cipher}hello world", cause: ErrInvalidBase64},
+		{name: "Data too short", value: "{cipher}", cause: ErrDataTooShort},
+		{name: "RSA failure", value: "{cipher}AQCE8/DTlSRAmt7KXjWe7FSlxD+e3Gv7pcq469QyYzhPuNmgOlzOZbze3S36e0Wzdqwzk/YBTd0GtywC56TCypAz6/LE/aUxz4WMPJkKpx3xKeiR1h7qr9embtt4ssixSeVkTbSdypGTEgJMMU65dYHjyypGipXD8JiebnysnwSYQSdbXKXxXq/U/+Z6r3mvk7yBKsDi4TAm99AzCMnBcwsDB2OnKTQSNWaq70w6T/XtzP78sDaBl73wMTRLjjh5jZ8gNH7ozG+oJ8jhwy6n+1D/3cO5uPhiDJi8XormYS6ydMEscx++lQDSBUPy0ukmM6l8horhyP456p61lYkrfiaHX58C/A2wraQ2nWLJY7mNWia6kR4Rn+HNi41FDIFw2Jc=", cause: ErrRSADecryption},
+		{name: "Unknown key alias", value: "{cipher}{key:secondary}AQA=", cause: ErrUnknownKeyAlias},
+	}
+	for _, tc := range tt {
+		input := "db:\n  password: " + tc.value + "\n"
+		err := NewConfigDecryptor(valueDecryptor, WithFileName("app.yml")).Decrypt(new(bytes.Buffer), strings.NewReader(input))
+		if !errors.Is(err, tc.cause) {
+			t.Errorf("%s: expected cause %v, actual %v", tc.name, tc.cause, err)
+		}
+		var decryptErr *DecryptError
+		if !errors.As(err, &decryptErr) {
+			t.Fatalf("%s: expected DecryptError, actual %T", tc.name, err)
+		}
+		if decryptErr.File != "app.yml" || decryptErr.Line != 2 || decryptErr.Column != 13 || decryptErr.Path != "db.password" {
+			t.Errorf("%s: unexpected position %v", tc.name, decryptErr)
+		}
+		if strings.Contains(err.Error(), strings.TrimPrefix(tc.value, "{cipher}")) && tc.value != "{cipher}" {
+			t.Errorf("%s: error echoes the cipher text: %v", tc.name, err)
+		}
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	valueDecryptor, err := NewValueDecryptor([]byte(privateKey))
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	input := "user: '{cipher}AQCE7t4KSgXRgRGRkJr4KhcS8Y5YsWzU07ac67ECLJPu6IbxkrkLn3mRl/FaTumJrbjX6+0gkG8e/TARjCj4tsVqx9Y8KK5yISaBHArKjyXDAJ71+nSsJAX/tcukONFGBqxYBkXH9OcXH8hoNagWWg/4pt3CwGw/wGgFU3dBLdvf8gu7S8YxCHWE5TSkUvxB/Gs/C5JLkklE3vz3ATYCnDTx1X8weQUxKeqOqe8AaElq8QkpVeJackkzsv2w6A8YydterEuELSjk5icLF0CKHlpD9x+emiprmaOADxjP526YinTlGnRsiDroaZ3avIURjUc+GCOt47i8grQIT1DmzUvailAMfsVgvnsSyKOO18VSqe11l9AKMnzEwqJ8cmHT3Kc='\npassword: '{cipher}{key:old}AQCE8/DTlSRAmt7KXjWe7FSlxD+e3Gv7pcq469QyYzhPuNmgOlzOZbze3S36e0Wzdqwzk/YBTd0GtywC56TCypAz6/LE/aUxz4WMPJkKpx3xKeiR1h7qr9embtt4ssixSeVkTbSdypGTEgJMMU65dYHjyypGipXD8JiebnysnwSYQSdbXKXxXq/U/+Z6r3mvk7yBKsDi4TAm99AzCMnBcwsDB2OnKTQSNWaq70w6T/XtzP78sDaBl73wMTRLjjh5jZ8gNH7ozG+oJ8jhwy6n+1D/3cO5uPhiDJi8XormYS6ydMEscx++lQDSBUPy0ukmM6l8horhyP456p61lYkrfiaHX58C/A2wraQ2nWLJY7mNWia6kR4Rn+HNi41FDIFw2Jc='\n"
+	report, err := NewConfigDecryptor(valueDecryptor, WithFileName("app.yml")).Check(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("check error: %v", err)
+	}
+	if report.Values != 2 {
+		t.Errorf("Values differ: expected 2, actual %d", report.Values)
+	}
+	if len(report.Failures) != 1 {
+		t.Fatalf("Expected 1 failure, actual %v", report.Failures)
+	}
+	expected := "app.yml:2:12 property 'password' key 'old': crypto/rsa: decryption error"
+	if actual := report.Failures[0].Error(); actual != expected {
+		t.Errorf("Errors differ: expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestDecryptConfigRedacted(t *testing.T) {
+	valueDecryptor, err := NewValueDecryptor([]byte(privateKey))
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	input := "user: '{cipher}AQCE7t4KSgXRgRGRkJr4KhcS8Y5YsWzU07ac67ECLJPu6IbxkrkLn3mRl/FaTumJrbjX6+0gkG8e/TARjCj4tsVqx9Y8KK5yISaBHArKjyXDAJ71+nSsJAX/tcukONFGBqxYBkXH9OcXH8hoNagWWg/4pt3CwGw/wGgFU3dBLdvf8gu7S8YxCHWE5TSkUvxB/Gs/C5JLkklE3vz3ATYCnDTx1X8weQUxKeqOqe8AaElq8QkpVeJackkzsv2w6A8YydterEuELSjk5icLF0CKHlpD9x+emiprmaOADxjP526YinTlGnRsiDroaZ3avIURjUc+GCOt47i8grQIT1DmzUvailAMfsVgvnsSyKOO18VSqe11l9AKMnzEwqJ8cmHT3Kc='\npassword: '{cipher}AQ=='\n"
+	tt := []struct {
+		name     string
+		redactor Redactor
+		expected string
+	}{
+		{
+			name:     "Fingerprint",
+			redactor: FingerprintRedactor(nil),
+			expected: "user: '<redacted:sha256:2c26b46b68ffc68f, len=3>'\npassword: '{cipher}AQ=='\n",
+		},
+		{
+			name:     "Keyed fingerprint",
+			redactor: FingerprintRedactor([]byte("key")),
+			expected: "user: '<redacted:hmac-sha256:6ea1d9f5e93a8f3a, len=3>'\npassword: '{cipher}AQ=='\n",
+		},
+		{
+			name:     "Mask",
+			redactor: MaskRedactor(""),
+			expected: "user: '******'\npassword: '{cipher}AQ=='\n",
+		},
+	}
+	for _, tc := range tt {
+		var output bytes.Buffer
+		report, err := NewConfigDecryptor(valueDecryptor, WithRedactor(tc.redactor), WithErrorPolicy(KeepCipherText)).DecryptWithReport(&output, strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: decrypt error: %v", tc.name, err)
+		}
+		if output.String() != tc.expected {
+			t.Errorf("%s: output differs: expected %q, actual %q", tc.name, tc.expected, output.String())
+		}
+		if len(report.Failures) != 1 {
+			t.Errorf("%s: expected 1 failure, actual %v", tc.name, report.Failures)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	valueDecryptor, err := NewValueDecryptor([]byte(privateKey))
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	tt := []struct {
+		name     string
+		value    string
+		prefixes map[string]string
+		stages   []string
+		error    string
+	}{
+		{
+			name:   "Valid",
+			value:  "{cipher}AQCE7t4KSgXRgRGRkJr4KhcS8Y5YsWzU07ac67ECLJPu6IbxkrkLn3mRl/FaTumJrbjX6+0gkG8e/TARjCj4tsVqx9Y8KK5yISaBHArKjyXDAJ71+nSsJAX/tcukONFGBqxYBkXH9OcXH8hoNagWWg/4pt3CwGw/wGgFU3dBLdvf8gu7S8YxCHWE5TSkUvxB/Gs/C5JLkklE3vz3ATYCnDTx1X8weQUxKeqOqe8AaElq8QkpVeJackkzsv2w6A8YydterEuELSjk5icLF0CKHlpD9x+emiprmaOADxjP526YinTlGnRsiDroaZ3avIURjUc+GCOt47i8grQIT1DmzUvailAMfsVgvnsSyKOO18VSqe11l9AKMnzEwqJ8cmHT3Kc=",
+			stages: []string{StageRSA, StageAES, StageUTF8},
+		},
+		{
+			name:     "Wrong key",
+			value:    "{cipher}{key:old}AQCE8/DTlSRAmt7KXjWe7FSlxD+e3Gv7pcq469QyYzhPuNmgOlzOZbze3S36e0Wzdqwzk/YBTd0GtywC56TCypAz6/LE/aUxz4WMPJkKpx3xKeiR1h7qr9embtt4ssixSeVkTbSdypGTEgJMMU65dYHjyypGipXD8JiebnysnwSYQSdbXKXxXq/U/+Z6r3mvk7yBKsDi4TAm99AzCMnBcwsDB2OnKTQSNWaq70w6T/XtzP78sDaBl73wMTRLjjh5jZ8gNH7ozG+oJ8jhwy6n+1D/3cO5uPhiDJi8XormYS6ydMEscx++lQDSBUPy0ukmM6l8horhyP456p61lYkrfiaHX58C/A2wraQ2nWLJY7mNWia6kR4Rn+HNi41FDIFw2Jc=",
+			prefixes: map[string]string{"key": "old"},
+			stages:   []string{StageRSA},
+		},
+		{
+			name:  "Too short",
+			value: "{cipher}AQ==",
+			error: "data too short to read session key length",
+		},
+	}
+	for _, tc := range tt {
+		envelope := valueDecryptor.InspectValue(tc.value)
+		if envelope.Error != tc.error {
+			t.Errorf("%s: errors differ: expected %v, actual %v", tc.name, tc.error, envelope.Error)
+		}
+		if len(tc.prefixes) != 0 && !reflect.DeepEqual(envelope.Prefixes, tc.prefixes) {
+			t.Errorf("%s: prefixes differ: expected %v, actual %v", tc.name, tc.prefixes, envelope.Prefixes)
+		}
+		if len(envelope.Stages) != len(tc.stages) {
+			t.Fatalf("%s: unexpected stages %v", tc.name, envelope.Stages)
+		}
+		for i, stage := range envelope.Stages {
+			// only the last stage may fail
+			if stage.Name != tc.stages[i] || stage.OK != (tc.name == "Valid" || i < len(tc.stages)-1) {
+				t.Errorf("%s: unexpected stage %v", tc.name, stage)
+			}
+		}
+		if tc.error == "" && (envelope.SessionKeyLength != 256 || envelope.SessionKeyBits != 2048 || !*envelope.MatchesKey || envelope.Layout != LayoutCBC || envelope.PayloadLength != 32) {
+			t.Errorf("%s: unexpected envelope %+v", tc.name, envelope)
+		}
+	}
+}
+
+type stubDecryptor string
+
+func (s stubDecryptor) DecryptValueWithKey(value string) ([]byte, string, error) {
+	return []byte(value), string(s), nil
+}
+
+func TestFormatDecryptor(t *testing.T) {
+	rsaValue := "{cipher}AQCE7t4KSgXRgRGRkJr4KhcS8Y5YsWzU07ac67ECLJPu6IbxkrkLn3mRl/FaTumJrbjX6+0gkG8e/TARjCj4tsVqx9Y8KK5yISaBHArKjyXDAJ71+nSsJAX/tcukONFGBqxYBkXH9OcXH8hoNagWWg/4pt3CwGw/wGgFU3dBLdvf8gu7S8YxCHWE5TSkUvxB/Gs/C5JLkklE3vz3ATYCnDTx1X8weQUxKeqOqe8AaElq8QkpVeJackkzsv2w6A8YydterEuELSjk5icLF0CKHlpD9x+emiprmaOADxjP526YinTlGnRsiDroaZ3avIURjUc+GCOt47i8grQIT1DmzUvailAMfsVgvnsSyKOO18VSqe11l9AKMnzEwqJ8cmHT3Kc="
+	kmsBlob := base64.StdEncoding.EncodeToString(append([]byte{0x01, 0x02}, make([]byte, 300)...))
+	tt := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "RSA", value: rsaValue, expected: "rsa"},
+		{name: "RSA with key", value: "{cipher}{key:old}" + strings.TrimPrefix(rsaValue, CipherPrefix), expected: "rsa"},
+		{name: "RSA with context", value: "{cipher}{context:a=b}" + strings.TrimPrefix(rsaValue, CipherPrefix), expected: "kms"},
+		{name: "KMS blob", value: "{cipher}" + kmsBlob, expected: "kms"},
+		{name: "KMS algorithm", value: "{cipher}{algorithm:RSAES_OAEP_SHA_256}" + kmsBlob, expected: "kms"},
+		{name: "plain", value: "foo", expected: "rsa"},
+	}
+	valueDecryptor := NewFormatDecryptor(stubDecryptor("rsa"), stubDecryptor("kms"))
+	for _, tc := range tt {
+		if _, actual, _ := valueDecryptor.DecryptValueWithKey(tc.value); actual != tc.expected {
+			t.Errorf("%s: expected %s decryptor, actual %s", tc.name, tc.expected, actual)
+		}
+	}
+	if NewFormatDecryptor(nil, stubDecryptor("kms")) != stubDecryptor("kms") {
+		t.Errorf("Expected the KMS decryptor only")
+	}
+}
+
+func TestDecryptConfigMarkers(t *testing.T) {
+	configDecryptor := NewConfigDecryptor(stubDecryptor("rsa"), WithMarker("ENC(", ")", stubDecryptor("jasypt")), WithMarker("${secret:", "}", stubDecryptor("custom")))
+	// an empty ENC() and ENC(...) with other characters are plain text
+	input := "a: ENC(abc=) {cipher}AQ== (ENC()\nb: '${secret:x}'\nc: ENC(not base64!)\n"
+	report, err := configDecryptor.Check(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("check error: %v", err)
+	}
+	expected := map[string]int{"rsa": 1, "jasypt": 1, "custom": 1}
+	if report.Values != 3 || !reflect.DeepEqual(report.Keys, expected) {
+		t.Errorf("Unexpected report %d %v", report.Values, report.Keys)
+	}
+	var output bytes.Buffer
+	if err = configDecryptor.Decrypt(&output, strings.NewReader(input)); err != nil || output.String() != input {
+		t.Errorf("Unexpected output %q %v", output.String(), err)
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	generated, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate key error: %v", err)
+	}
+	otherKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(generated)})
+	keyringDecryptor, err := NewValueDecryptor(otherKey, WithKeyring(otherKey, []byte(privateKey)))
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	fingerprints := keyringDecryptor.Fingerprints()
+	if fingerprint, _ := Fingerprint(&generated.PublicKey); len(fingerprints) != 2 || fingerprints[0] != fingerprint {
+		t.Fatalf("Unexpected keyring %v", fingerprints)
+	}
+	value := "{cipher}AQCE7t4KSgXRgRGRkJr4KhcS8Y5YsWzU07ac67ECLJPu6IbxkrkLn3mRl/FaTumJrbjX6+0gkG8e/TARjCj4tsVqx9Y8KK5yISaBHArKjyXDAJ71+nSsJAX/tcukONFGBqxYBkXH9OcXH8hoNagWWg/4pt3CwGw/wGgFU3dBLdvf8gu7S8YxCHWE5TSkUvxB/Gs/C5JLkklE3vz3ATYCnDTx1X8weQUxKeqOqe8AaElq8QkpVeJackkzsv2w6A8YydterEuELSjk5icLF0CKHlpD9x+emiprmaOADxjP526YinTlGnRsiDroaZ3avIURjUc+GCOt47i8grQIT1DmzUvailAMfsVgvnsSyKOO18VSqe11l9AKMnzEwqJ8cmHT3Kc="
+	for i := 0; i < 2; i++ {
+		plainText, fingerprint, err := keyringDecryptor.DecryptValueWithKey(value)
+		if err != nil {
+			t.Fatalf("decrypt error: %v", err)
+		}
+		if string(plainText) != "foo" || fingerprint != fingerprints[1] {
+			t.Errorf("Unexpected result %q %s", plainText, fingerprint)
+		}
+	}
+	if len(keyringDecryptor.cache.entries) != 1 {
+		t.Errorf("Expected cached key, actual %v", keyringDecryptor.cache.entries)
+	}
+
+	var output bytes.Buffer
+	report, err := NewConfigDecryptor(keyringDecryptor, WithErrorPolicy(KeepCipherText)).DecryptWithReport(&output, strings.NewReader("a: '"+value+"'\nb: '"+value+"'\n"))
+	if err != nil {
+		t.Fatalf("decrypt error: %v", err)
+	}
+	if !reflect.DeepEqual(report.Keys, map[string]int{fingerprints[1]: 2}) {
+		t.Errorf("Unexpected keys %v", report.Keys)
+	}
+
+	withoutKey, err := NewValueDecryptor(otherKey, WithKeyring(otherKey))
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	if _, _, err = withoutKey.DecryptValueWithKey(value); err != ErrNoMatchingKey {
+		t.Errorf("Expected no matching key error, actual %v", err)
+	}
+	if _, _, err = withoutKey.DecryptValueWithKey("{cipher}AQ=="); !errors.Is(err, ErrDataTooShort) {
+		t.Errorf("Expected data too short error, actual %v", err)
+	}
+}
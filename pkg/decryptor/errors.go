@@ -0,0 +1,57 @@
+package decryptor
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidBase64 is returned when the {cipher} value is not valid base64.
+	ErrInvalidBase64 = errors.New("value cannot be base64 decoded")
+	// ErrDataTooShort is returned when the decoded value is shorter than the envelope requires.
+	ErrDataTooShort = errors.New("data too short")
+	// ErrRSADecryption is returned when the session key cannot be decrypted with the private key.
+	ErrRSADecryption = rsa.ErrDecryption
+	// ErrInvalidPadding is returned when the AES cipher text or its padding is malformed.
+	ErrInvalidPadding = errors.New("invalid padding")
+	// ErrUnknownKeyAlias is returned when the {key:alias} prefix does not match the configured key.
+	ErrUnknownKeyAlias = errors.New("unknown key alias")
+)
+
+// DecryptError describes a value which could not be decrypted and where it was found.
+type DecryptError struct {
+	File     string
+	Line     int
+	Column   int
+	Path     string
+	KeyAlias string
+	Err      error
+}
+
+func (e *DecryptError) Error() string {
+	var parts []string
+	switch {
+	case e.File != "" && e.Line > 0:
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column))
+	case e.File != "":
+		parts = append(parts, e.File)
+	case e.Line > 0:
+		parts = append(parts, fmt.Sprintf("line %d column %d", e.Line, e.Column))
+	}
+	if e.Path != "" {
+		parts = append(parts, fmt.Sprintf("property '%s'", e.Path))
+	}
+	if e.KeyAlias != "" {
+		parts = append(parts, fmt.Sprintf("key '%s'", e.KeyAlias))
+	}
+	if len(parts) == 0 {
+		return e.Err.Error()
+	}
+	return strings.Join(parts, " ") + ": " + e.Err.Error()
+}
+
+func (e *DecryptError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,57 @@
+package decryptor
+
+import (
+	"crypto/aes"
+	"strings"
+)
+
+// CipherDecryptor decrypts a {cipher} value and returns the identifier of the key which decrypted it,
+// implemented by ValueDecryptor and by the KMS decryptor.
+type CipherDecryptor interface {
+	DecryptValueWithKey(value string) ([]byte, string, error)
+}
+
+// kmsPrefixes are the prefixes only the spring-cloud-config-aws-kms TextEncryptor writes.
+var kmsPrefixes = []string{"context", "algorithm"}
+
+// ParseCipherValue removes the {cipher} marker and returns the base64 data and the {name:value} prefixes.
+func ParseCipherValue(value string) (string, map[string]string) {
+	return splitPrefixes(strings.TrimPrefix(value, CipherPrefix))
+}
+
+// IsRSAEnvelope reports whether the {cipher} value looks like a Spring RSA envelope:
+// no KMS prefixes, a session key of a common RSA modulus size and at least an AES block of payload after the IV.
+// KMS ciphertext blobs start with the version bytes 0x01 0x02, which do not read as a common session key length.
+func IsRSAEnvelope(value string) bool {
+	envelope := Inspect(value)
+	for _, name := range kmsPrefixes {
+		if _, ok := envelope.Prefixes[name]; ok {
+			return false
+		}
+	}
+	return envelope.Error == "" && envelope.SessionKeyBits != 0 && envelope.PayloadLength >= 2*aes.BlockSize
+}
+
+type formatDecryptor struct {
+	rsa   CipherDecryptor
+	other CipherDecryptor
+}
+
+// NewFormatDecryptor decrypts RSA envelopes with rsa and all other {cipher} values with other, e.g. a KMS decryptor.
+// If one of them is nil, all values are decrypted with the other one.
+func NewFormatDecryptor(rsa, other CipherDecryptor) CipherDecryptor {
+	switch {
+	case other == nil:
+		return rsa
+	case rsa == nil:
+		return other
+	}
+	return &formatDecryptor{rsa: rsa, other: other}
+}
+
+func (d *formatDecryptor) DecryptValueWithKey(value string) ([]byte, string, error) {
+	if !strings.HasPrefix(value, CipherPrefix) || IsRSAEnvelope(value) {
+		return d.rsa.DecryptValueWithKey(value)
+	}
+	return d.other.DecryptValueWithKey(value)
+}
@@ -0,0 +1,154 @@
+package decryptor
+
+import (
+	"crypto/aes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+var errInvalidUTF8 = errors.New("plain text is not valid UTF-8")
+
+// Layout is the AES mode the payload of an envelope looks like.
+type Layout string
+
+const (
+	LayoutCBC     Layout = "cbc"
+	LayoutGCM     Layout = "gcm"
+	LayoutUnknown Layout = "unknown"
+)
+
+// CommonKeySizes are the RSA modulus sizes in bits a session key length is compared with.
+var CommonKeySizes = []int{1024, 2048, 3072, 4096}
+
+// Stage names of the decryption.
+const (
+	StageRSA  = "rsa-unwrap"
+	StageAES  = "kdf-padding"
+	StageUTF8 = "utf-8"
+)
+
+// Stage is the result of a single decryption stage, Error is empty if the stage succeeded.
+type Stage struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Envelope describes the structure of a {cipher} value without revealing its plain text.
+type Envelope struct {
+	Prefixes map[string]string `json:"prefixes,omitempty"`
+	// Length is the number of base64 decoded bytes
+	Length int `json:"length"`
+	// SessionKeyLength is the declared length of the RSA encrypted session key
+	SessionKeyLength int `json:"sessionKeyLength"`
+	// SessionKeyBits is the common RSA modulus size matching the session key length, 0 if none matches
+	SessionKeyBits int `json:"sessionKeyBits,omitempty"`
+	// KeyBits is the modulus size of the inspecting key, 0 without key
+	KeyBits int `json:"keyBits,omitempty"`
+	// MatchesKey reports whether the session key length equals the modulus size of the inspecting key
+	MatchesKey *bool `json:"matchesKey,omitempty"`
+	// IV is the hex encoded AES initialization vector or GCM nonce
+	IV string `json:"iv,omitempty"`
+	// PayloadLength is the number of bytes after the session key including the IV
+	PayloadLength int    `json:"payloadLength"`
+	BlockAligned  bool   `json:"blockAligned"`
+	Layout        Layout `json:"layout"`
+	// Error is the reason the envelope cannot be parsed
+	Error  string  `json:"error,omitempty"`
+	Stages []Stage `json:"stages,omitempty"`
+}
+
+// Inspect parses the {cipher} value the way the decryption does and describes its structure.
+func Inspect(value string) *Envelope {
+	envelope, _ := parseEnvelope(value)
+	return envelope
+}
+
+func parseEnvelope(value string) (*Envelope, []byte) {
+	result := &Envelope{Layout: LayoutUnknown}
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, CipherPrefix) {
+		result.Error = "value does not start with " + CipherPrefix
+		return result, nil
+	}
+	value, prefixes := splitPrefixes(strings.TrimPrefix(value, CipherPrefix))
+	if len(prefixes) != 0 {
+		result.Prefixes = prefixes
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		result.Error = ErrInvalidBase64.Error() + ": " + err.Error()
+		return result, nil
+	}
+	result.Length = len(data)
+	if len(data) < 2 {
+		result.Error = ErrDataTooShort.Error() + " to read session key length"
+		return result, data
+	}
+	result.SessionKeyLength = int(binary.BigEndian.Uint16(data[0:2]))
+	for _, bits := range CommonKeySizes {
+		if bits/8 == result.SessionKeyLength {
+			result.SessionKeyBits = bits
+		}
+	}
+	if len(data) < result.SessionKeyLength+2 {
+		result.Error = ErrDataTooShort.Error() + " to read session key cipher text"
+		return result, data
+	}
+	payload := data[2+result.SessionKeyLength:]
+	result.PayloadLength = len(payload)
+	if len(payload) >= aes.BlockSize {
+		result.IV = hex.EncodeToString(payload[:aes.BlockSize])
+	}
+	result.BlockAligned = len(payload)%aes.BlockSize == 0
+	switch {
+	case len(payload) >= 2*aes.BlockSize && result.BlockAligned:
+		// a GCM payload of a plain text with a length multiple of the block size is aligned as well
+		result.Layout = LayoutCBC
+	case len(payload) >= 2*aes.BlockSize:
+		result.Layout = LayoutGCM
+	}
+	return result, data
+}
+
+// InspectValue describes the structure of the {cipher} value and reports which decryption stage fails.
+// The plain text is cleared and never returned.
+func (d ValueDecryptor) InspectValue(value string) *Envelope {
+	result, data := parseEnvelope(value)
+	result.KeyBits = d.privateKey.N.BitLen()
+	if result.Length >= 2 {
+		matches := result.SessionKeyLength == d.privateKey.Size()
+		result.MatchesKey = &matches
+	}
+	if result.Error != "" {
+		return result
+	}
+
+	stage := func(name string, err error) bool {
+		s := Stage{Name: name, OK: err == nil}
+		if err != nil {
+			s.Error = err.Error()
+		}
+		result.Stages = append(result.Stages, s)
+		return err == nil
+	}
+	key, payload, err := d.unwrapKey(data)
+	if !stage(StageRSA, err) {
+		return result
+	}
+	plainText, err := d.decryptPayload(key, payload)
+	Zero(key)
+	defer Zero(plainText)
+	if !stage(StageAES, err) {
+		return result
+	}
+	if !utf8.Valid(plainText) {
+		err = errInvalidUTF8
+	}
+	stage(StageUTF8, err)
+	return result
+}
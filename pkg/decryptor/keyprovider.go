@@ -0,0 +1,416 @@
+package decryptor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrKeyNotFound is returned by a KeyProvider whose source is not set or is empty.
+var ErrKeyNotFound = errors.New("private key not found")
+
+// KeyProvider supplies the PEM or DER encoded private keys of a decryptor.
+// The first key is the primary key, the others are tried as keyring, see WithKeyring.
+type KeyProvider interface {
+	Keys() ([][]byte, error)
+	// String describes the source without revealing the keys
+	String() string
+}
+
+type fileKeyProvider struct {
+	path string
+}
+
+// NewFileKeyProvider reads the key from the file on every call.
+func NewFileKeyProvider(path string) KeyProvider {
+	return &fileKeyProvider{path: path}
+}
+
+func (p *fileKeyProvider) Keys() ([][]byte, error) {
+	key, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("key file reading error: %v", err)
+	}
+	if len(bytes.TrimSpace(key)) == 0 {
+		return nil, fmt.Errorf("%w: file %s is empty", ErrKeyNotFound, p.path)
+	}
+	return [][]byte{key}, nil
+}
+
+func (p *fileKeyProvider) String() string {
+	return "file://" + p.path
+}
+
+type envKeyProvider struct {
+	name   string
+	base64 bool
+}
+
+// NewEnvKeyProvider reads the key from the environment variable, or if it is not set
+// from the file named by the variable with the _FILE suffix e.g. ENCRYPT_KEY_FILE.
+// If base64 is set the key is base64 decoded.
+func NewEnvKeyProvider(name string, base64 bool) KeyProvider {
+	return &envKeyProvider{name: name, base64: base64}
+}
+
+func (p *envKeyProvider) Keys() ([][]byte, error) {
+	var key []byte
+	if value := os.Getenv(p.name); value != "" {
+		key = []byte(value)
+	} else if file := os.Getenv(p.name + "_FILE"); file != "" {
+		keys, err := NewFileKeyProvider(file).Keys()
+		if err != nil {
+			return nil, err
+		}
+		key = keys[0]
+	} else {
+		return nil, fmt.Errorf("%w: environment variable %s / %s_FILE is not set", ErrKeyNotFound, p.name, p.name)
+	}
+	if p.base64 {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(key)))
+		if err != nil {
+			return nil, fmt.Errorf("key %s base64 decoding error: %v", p.name, err)
+		}
+		key = decoded
+	}
+	return [][]byte{key}, nil
+}
+
+func (p *envKeyProvider) String() string {
+	if p.base64 {
+		return "env://" + p.name + "?encoding=base64"
+	}
+	return "env://" + p.name
+}
+
+// readerKeyProvider reads the key once, the reader cannot be read again.
+type readerKeyProvider struct {
+	name   string
+	reader io.Reader
+	once   sync.Once
+	key    []byte
+	err    error
+}
+
+// NewReaderKeyProvider reads the key once from the reader e.g. stdin.
+func NewReaderKeyProvider(name string, reader io.Reader) KeyProvider {
+	return &readerKeyProvider{name: name, reader: reader}
+}
+
+// NewFDKeyProvider reads the key once from the inherited file descriptor e.g. 3 with `3< private.pem`.
+func NewFDKeyProvider(fd uintptr) KeyProvider {
+	name := "fd://" + strconv.FormatUint(uint64(fd), 10)
+	return NewReaderKeyProvider(name, os.NewFile(fd, name))
+}
+
+func (p *readerKeyProvider) Keys() ([][]byte, error) {
+	p.once.Do(func() {
+		p.key, p.err = ioutil.ReadAll(p.reader)
+		if closer, ok := p.reader.(io.Closer); ok && p.reader != os.Stdin {
+			_ = closer.Close()
+		}
+		if p.err != nil {
+			p.err = fmt.Errorf("key %s reading error: %v", p.name, p.err)
+		} else if len(bytes.TrimSpace(p.key)) == 0 {
+			p.err = fmt.Errorf("%w: %s is empty", ErrKeyNotFound, p.name)
+		}
+	})
+	if p.err != nil {
+		return nil, p.err
+	}
+	return [][]byte{p.key}, nil
+}
+
+func (p *readerKeyProvider) String() string {
+	return p.name
+}
+
+type dirKeyProvider struct {
+	dir string
+}
+
+// NewDirKeyProvider reads every file of the directory as key on every call, sorted by name.
+// Hidden files are skipped.
+func NewDirKeyProvider(dir string) KeyProvider {
+	return &dirKeyProvider{dir: dir}
+}
+
+func (p *dirKeyProvider) Keys() ([][]byte, error) {
+	return readKeyDir(p.dir)
+}
+
+func (p *dirKeyProvider) String() string {
+	return "dir://" + p.dir
+}
+
+// readKeyDir reads the keys of the directory sorted by file name.
+func readKeyDir(dir string) ([][]byte, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("key directory reading error: %v", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	var keys [][]byte
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		// follow the symbolic links of mounted secrets
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		key, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("key file reading error: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: directory %s has no key files", ErrKeyNotFound, dir)
+	}
+	return keys, nil
+}
+
+// mountedKeyProvider caches the keys of a mounted secret directory until the files change.
+type mountedKeyProvider struct {
+	dir   string
+	mu    sync.Mutex
+	stamp string
+	keys  [][]byte
+}
+
+// NewMountedKeyProvider reads the keys of a Kubernetes secret style mounted directory like NewDirKeyProvider
+// and reloads them when the files change. Kubernetes replaces the ..data symbolic link on updates,
+// without it the modification times and sizes of the files are compared.
+func NewMountedKeyProvider(dir string) KeyProvider {
+	return &mountedKeyProvider{dir: dir}
+}
+
+func (p *mountedKeyProvider) Keys() ([][]byte, error) {
+	stamp, err := dirStamp(p.dir)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.keys != nil && stamp == p.stamp {
+		return p.keys, nil
+	}
+	keys, err := readKeyDir(p.dir)
+	if err != nil {
+		return nil, err
+	}
+	p.keys, p.stamp = keys, stamp
+	return keys, nil
+}
+
+func (p *mountedKeyProvider) String() string {
+	return "mount://" + p.dir
+}
+
+// dirStamp returns the target of the ..data link or the names, sizes and modification times of the files.
+func dirStamp(dir string) (string, error) {
+	if target, err := os.Readlink(filepath.Join(dir, "..data")); err == nil {
+		return target, nil
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("key directory reading error: %v", err)
+	}
+	var sb strings.Builder
+	for _, entry := range entries {
+		info, err := os.Stat(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		_, _ = fmt.Fprintf(&sb, "%s %d %d\n", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return sb.String(), nil
+}
+
+type firstKeyProvider struct {
+	providers []KeyProvider
+}
+
+// NewFirstKeyProvider uses the first provider which does not return ErrKeyNotFound.
+func NewFirstKeyProvider(providers ...KeyProvider) KeyProvider {
+	return &firstKeyProvider{providers: providers}
+}
+
+func (p *firstKeyProvider) Keys() ([][]byte, error) {
+	for _, provider := range p.providers {
+		keys, err := provider.Keys()
+		if !errors.Is(err, ErrKeyNotFound) {
+			return keys, err
+		}
+	}
+	return nil, fmt.Errorf("%w in %s", ErrKeyNotFound, p)
+}
+
+func (p *firstKeyProvider) String() string {
+	names := make([]string, 0, len(p.providers))
+	for _, provider := range p.providers {
+		names = append(names, provider.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// IsStdinKeySource reports whether the key source URI reads the standard input, as parsed by ParseKeyProvider.
+func IsStdinKeySource(uri string) bool {
+	scheme, rest := splitKeySource(uri)
+	switch scheme {
+	case "stdin":
+		return true
+	case "fd":
+		fd, err := strconv.ParseUint(rest, 10, 32)
+		return err == nil && fd == 0
+	}
+	return false
+}
+
+// splitKeySource returns the scheme of the key source URI and the rest after ://, a plain path is a file.
+func splitKeySource(uri string) (string, string) {
+	if uri == "-" {
+		return "stdin", ""
+	}
+	i := strings.Index(uri, "://")
+	if i == -1 {
+		return "file", uri
+	}
+	return uri[:i], uri[i+3:]
+}
+
+// ParseKeyProvider creates the provider of the key source URI:
+//
+//	file://<path> or <path>         the key file
+//	env://<name>[?encoding=base64]  the environment variable or the file in <name>_FILE
+//	fd://<n>                        the inherited file descriptor
+//	stdin:// or -                   the standard input
+//	dir://<path>                    every file of the directory
+//	mount://<path>                  every file of the mounted secret directory, reloaded when it changes
+func ParseKeyProvider(uri string) (KeyProvider, error) {
+	scheme, rest := splitKeySource(uri)
+	switch scheme {
+	case "file":
+		return NewFileKeyProvider(rest), nil
+	case "env":
+		name, query := rest, ""
+		if j := strings.Index(rest, "?"); j != -1 {
+			name, query = rest[:j], rest[j+1:]
+		}
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key source '%s': %v", uri, err)
+		}
+		encoding := values.Get("encoding")
+		if name == "" || (encoding != "" && encoding != "base64") {
+			return nil, fmt.Errorf("invalid key source '%s', expected env://<name>[?encoding=base64]", uri)
+		}
+		return NewEnvKeyProvider(name, encoding == "base64"), nil
+	case "fd":
+		fd, err := strconv.ParseUint(rest, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key source '%s', expected fd://<number>", uri)
+		}
+		return NewFDKeyProvider(uintptr(fd)), nil
+	case "stdin":
+		return NewReaderKeyProvider("stdin://", os.Stdin), nil
+	case "dir":
+		return NewDirKeyProvider(rest), nil
+	case "mount":
+		return NewMountedKeyProvider(rest), nil
+	}
+	return nil, fmt.Errorf("unknown key source scheme '%s', expected file, env, fd, stdin, dir or mount", scheme)
+}
+
+// NewValueDecryptorFromProvider creates the decryptor from the first key of the provider
+// and uses the other keys as keyring.
+func NewValueDecryptorFromProvider(provider KeyProvider, options ...ValueDecryptorOption) (*ValueDecryptor, error) {
+	keys, err := provider.Keys()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) > 1 {
+		options = append([]ValueDecryptorOption{WithKeyring(keys[1:]...)}, options...)
+	}
+	return NewValueDecryptor(keys[0], options...)
+}
+
+// providerDecryptor creates a new value decryptor whenever the keys of the provider change.
+type providerDecryptor struct {
+	provider       KeyProvider
+	options        []ValueDecryptorOption
+	configOptions  []ConfigDecryptorOption
+	mu             sync.Mutex
+	version        [sha256.Size]byte
+	valueDecryptor *ValueDecryptor
+}
+
+// NewDecryptorWithKeyProvider creates a Decryptor which reads the keys from the provider on every call,
+// so keys of a mounted secret are reloaded without restart.
+func NewDecryptorWithKeyProvider(provider KeyProvider, options []ValueDecryptorOption, configOptions ...ConfigDecryptorOption) (Decryptor, error) {
+	result := &providerDecryptor{provider: provider, options: options, configOptions: configOptions}
+	if _, err := result.current(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (d *providerDecryptor) current() (*ValueDecryptor, error) {
+	keys, err := d.provider.Keys()
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	for _, key := range keys {
+		_, _ = fmt.Fprintf(hash, "%d\x00", len(key))
+		_, _ = hash.Write(key)
+	}
+	var version [sha256.Size]byte
+	copy(version[:], hash.Sum(nil))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.valueDecryptor != nil && version == d.version {
+		return d.valueDecryptor, nil
+	}
+	valueDecryptor, err := NewValueDecryptorFromProvider(staticKeyProvider(keys), d.options...)
+	if err != nil {
+		return nil, err
+	}
+	d.valueDecryptor, d.version = valueDecryptor, version
+	return valueDecryptor, nil
+}
+
+func (d *providerDecryptor) Decrypt(output io.Writer, input io.Reader) error {
+	valueDecryptor, err := d.current()
+	if err != nil {
+		return err
+	}
+	return NewConfigDecryptor(valueDecryptor, d.configOptions...).Decrypt(output, input)
+}
+
+type staticKeyProvider [][]byte
+
+func (p staticKeyProvider) Keys() ([][]byte, error) {
+	return p, nil
+}
+
+func (p staticKeyProvider) String() string {
+	return "static"
+}
@@ -0,0 +1,141 @@
+package decryptor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeyProviders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "private.pem")
+	if err = ioutil.WriteFile(keyFile, []byte(privateKey), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keyDir := filepath.Join(dir, "keys")
+	if err = os.Mkdir(keyDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"b.pem", "a.pem", ".hidden"} {
+		if err = ioutil.WriteFile(filepath.Join(keyDir, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = os.Setenv("TEST_KEY_FILE", keyFile)
+	_ = os.Setenv("TEST_KEY_BASE64", base64.StdEncoding.EncodeToString([]byte(privateKey)))
+	defer os.Unsetenv("TEST_KEY_FILE")
+	defer os.Unsetenv("TEST_KEY_BASE64")
+
+	tests := []struct {
+		name     string
+		uri      string
+		expected []string
+		err      string
+	}{
+		{name: "path", uri: keyFile, expected: []string{privateKey}},
+		{name: "file", uri: "file://" + keyFile, expected: []string{privateKey}},
+		{name: "env file", uri: "env://TEST_KEY", expected: []string{privateKey}},
+		{name: "env base64", uri: "env://TEST_KEY_BASE64?encoding=base64", expected: []string{privateKey}},
+		{name: "env not set", uri: "env://TEST_MISSING", err: "private key not found: environment variable TEST_MISSING / TEST_MISSING_FILE is not set"},
+		{name: "env encoding", uri: "env://TEST_KEY?encoding=hex", err: "invalid key source 'env://TEST_KEY?encoding=hex', expected env://<name>[?encoding=base64]"},
+		{name: "dir", uri: "dir://" + keyDir, expected: []string{"a.pem", "b.pem"}},
+		{name: "mount", uri: "mount://" + keyDir, expected: []string{"a.pem", "b.pem"}},
+		{name: "fd", uri: "fd://x", err: "invalid key source 'fd://x', expected fd://<number>"},
+		{name: "scheme", uri: "vault://secret", err: "unknown key source scheme 'vault', expected file, env, fd, stdin, dir or mount"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, err := ParseKeyProvider(tc.uri)
+			var keys [][]byte
+			if err == nil {
+				keys, err = provider.Keys()
+			}
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected error %q, actual %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("keys error: %v", err)
+			}
+			actual := make([]string, 0, len(keys))
+			for _, key := range keys {
+				actual = append(actual, string(key))
+			}
+			if strings.Join(actual, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Keys differ: expected %v, actual %v", tc.expected, actual)
+			}
+		})
+	}
+
+	provider := NewFirstKeyProvider(NewEnvKeyProvider("TEST_MISSING", false), NewReaderKeyProvider("stdin://", strings.NewReader(privateKey)))
+	for i := 0; i < 2; i++ {
+		if keys, err := provider.Keys(); err != nil || string(keys[0]) != privateKey {
+			t.Errorf("Unexpected first provider keys %v", err)
+		}
+	}
+	if _, err = NewFirstKeyProvider(NewEnvKeyProvider("TEST_MISSING", false)).Keys(); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected key not found error, actual %v", err)
+	}
+	for uri, expected := range map[string]bool{
+		"-": true, "stdin://": true, "stdin://x": true, "fd://0": true, "fd://00": true,
+		"fd://3": false, "fd://x": false, "file://-": false, "env://STDIN": false, "stdin": false,
+	} {
+		if actual := IsStdinKeySource(uri); actual != expected {
+			t.Errorf("%s: expected stdin %v, actual %v", uri, expected, actual)
+		}
+	}
+}
+
+func TestMountedKeyProviderReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// the layout of a Kubernetes secret volume
+	mount := func(version, content string) {
+		if err := os.Mkdir(filepath.Join(dir, version), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, version, "private.pem"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.Remove(filepath.Join(dir, "..data"))
+		if err := os.Symlink(version, filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mount("..v1", "bogus")
+	if err = os.Symlink(filepath.Join("..data", "private.pem"), filepath.Join(dir, "private.pem")); err != nil {
+		t.Fatal(err)
+	}
+
+	value := "{cipher}AQCE7t4KSgXRgRGRkJr4KhcS8Y5YsWzU07ac67ECLJPu6IbxkrkLn3mRl/FaTumJrbjX6+0gkG8e/TARjCj4tsVqx9Y8KK5yISaBHArKjyXDAJ71+nSsJAX/tcukONFGBqxYBkXH9OcXH8hoNagWWg/4pt3CwGw/wGgFU3dBLdvf8gu7S8YxCHWE5TSkUvxB/Gs/C5JLkklE3vz3ATYCnDTx1X8weQUxKeqOqe8AaElq8QkpVeJackkzsv2w6A8YydterEuELSjk5icLF0CKHlpD9x+emiprmaOADxjP526YinTlGnRsiDroaZ3avIURjUc+GCOt47i8grQIT1DmzUvailAMfsVgvnsSyKOO18VSqe11l9AKMnzEwqJ8cmHT3Kc="
+	provider := NewMountedKeyProvider(dir)
+	if _, err = NewDecryptorWithKeyProvider(provider, nil); err == nil {
+		t.Fatalf("Expected invalid key error")
+	}
+	mount("..v2", privateKey)
+	decryptor, err := NewDecryptorWithKeyProvider(provider, nil)
+	if err != nil {
+		t.Fatalf("create decryptor error: %v", err)
+	}
+	var output bytes.Buffer
+	if err = decryptor.Decrypt(&output, strings.NewReader("a: '"+value+"'\n")); err != nil {
+		t.Fatalf("decrypt error: %v", err)
+	}
+	if output.String() != "a: 'foo'\n" {
+		t.Errorf("Unexpected output %q", output.String())
+	}
+}
@@ -0,0 +1,149 @@
+package decryptor
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"sync"
+
+	perrors "github.com/pkg/errors"
+)
+
+// ErrNoMatchingKey is returned when no key of the keyring decrypts the value.
+// The failing stage is not reported, so the keyring cannot be used as padding oracle.
+var ErrNoMatchingKey = errors.New("no key in the keyring can decrypt the value")
+
+type keyringEntry struct {
+	privateKey  *rsa.PrivateKey
+	fingerprint string
+}
+
+// keyCache remembers which keyring entry decrypted a cipher text, by the SHA-256 of the cipher text.
+type keyCache struct {
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]int
+}
+
+func (c *keyCache) get(sum [sha256.Size]byte) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, ok := c.entries[sum]
+	return i, ok
+}
+
+func (c *keyCache) put(sum [sha256.Size]byte, i int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[sum] = i
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the DER encoded PKIX public key e.g. SHA256:Vx1s...,
+// it does not depend on the encoding the key was read from.
+func Fingerprint(key *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", perrors.Wrap(err, "public key encoding error")
+	}
+	sum := sha256.Sum256(der)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]), nil
+}
+
+// WithKeyring adds candidate private keys which are tried in the given order after the key of the decryptor,
+// for values without {key:} prefix encrypted with one of several historical keys.
+// Which key decrypted a value is cached by the SHA-256 of the cipher text.
+func WithKeyring(keys ...[]byte) ValueDecryptorOption {
+	return func(decryptor *ValueDecryptor) error {
+		if len(decryptor.keyring) == 0 {
+			decryptor.keyring = []keyringEntry{{privateKey: decryptor.privateKey, fingerprint: decryptor.fingerprint}}
+			decryptor.cache = &keyCache{entries: make(map[[sha256.Size]byte]int)}
+		}
+	next:
+		for i, key := range keys {
+			privateKey, err := ParsePrivateKey(key)
+			if err != nil {
+				return perrors.Wrapf(err, "keyring key %d", i+1)
+			}
+			fingerprint, err := Fingerprint(&privateKey.PublicKey)
+			if err != nil {
+				return perrors.Wrapf(err, "keyring key %d", i+1)
+			}
+			for _, entry := range decryptor.keyring {
+				if entry.fingerprint == fingerprint {
+					continue next
+				}
+			}
+			decryptor.keyring = append(decryptor.keyring, keyringEntry{privateKey: privateKey, fingerprint: fingerprint})
+		}
+		return nil
+	}
+}
+
+// Fingerprints returns the fingerprints of the keys in the order they are tried.
+func (d ValueDecryptor) Fingerprints() []string {
+	if len(d.keyring) == 0 {
+		return []string{d.fingerprint}
+	}
+	result := make([]string, 0, len(d.keyring))
+	for _, entry := range d.keyring {
+		result = append(result, entry.fingerprint)
+	}
+	return result
+}
+
+// decryptWithKeyring decrypts the data with the cached key or with the first key of the keyring which succeeds.
+// Every key runs every stage even after a failed RSA decryption and the same error is returned for all failures,
+// so neither the timing nor the result reveals which stage failed.
+func (d ValueDecryptor) decryptWithKeyring(data []byte) ([]byte, string, error) {
+	// the envelope structure does not depend on the key
+	if _, err := sessionKeyLength(data); err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	if i, ok := d.cache.get(sum); ok {
+		if plainText, ok := d.tryKey(d.keyring[i].privateKey, data); ok {
+			return plainText, d.keyring[i].fingerprint, nil
+		}
+	}
+	var (
+		result []byte
+		winner = -1
+	)
+	for i, entry := range d.keyring {
+		plainText, ok := d.tryKey(entry.privateKey, data)
+		if ok && winner == -1 {
+			result, winner = plainText, i
+		} else {
+			Zero(plainText)
+		}
+	}
+	if winner == -1 {
+		return nil, "", ErrNoMatchingKey
+	}
+	d.cache.put(sum, winner)
+	return result, d.keyring[winner].fingerprint, nil
+}
+
+// tryKey decrypts the data with the private key, a failed RSA decryption continues with a random session key.
+func (d ValueDecryptor) tryKey(privateKey *rsa.PrivateKey, data []byte) ([]byte, bool) {
+	sessionKey, payload, err := d.unwrapKeyWith(privateKey, data)
+	rsaOK := err == nil
+	if !rsaOK {
+		// the length of the random secret Spring encrypts
+		secret := make([]byte, 16)
+		if _, err = rand.Read(secret); err != nil {
+			return nil, false
+		}
+		sessionKey = deriveKey(secret, d.salt)
+	}
+	defer Zero(sessionKey)
+	// the CBC decryption works in place, the data is needed for the next key
+	plainText, err := d.decryptPayload(sessionKey, append([]byte(nil), payload...))
+	if err != nil || !rsaOK {
+		Zero(plainText)
+		return nil, false
+	}
+	return plainText, true
+}
@@ -0,0 +1,84 @@
+package decryptor
+
+import (
+	"fmt"
+	"strings"
+
+	merror "github.com/grepplabs/spring-config-decryptor/pkg/errors"
+)
+
+const defaultPlaceholder = "<n/a>"
+
+// ErrorPolicy defines how ConfigDecryptor handles values which cannot be decrypted.
+type ErrorPolicy int
+
+const (
+	// FailFast aborts the decryption on the first failure.
+	FailFast ErrorPolicy = iota
+	// KeepCipherText leaves the {cipher} value untouched.
+	KeepCipherText
+	// ReplaceWithPlaceholder replaces the {cipher} value with a placeholder, as Spring does.
+	ReplaceWithPlaceholder
+	// DropLine removes the whole line containing the value.
+	DropLine
+)
+
+var errorPolicyNames = map[ErrorPolicy]string{
+	FailFast:               "fail",
+	KeepCipherText:         "keep",
+	ReplaceWithPlaceholder: "placeholder",
+	DropLine:               "drop",
+}
+
+func (p ErrorPolicy) String() string {
+	if name, ok := errorPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("ErrorPolicy(%d)", int(p))
+}
+
+func ParseErrorPolicy(value string) (ErrorPolicy, error) {
+	for policy, name := range errorPolicyNames {
+		if strings.EqualFold(name, value) {
+			return policy, nil
+		}
+	}
+	return FailFast, fmt.Errorf("unknown error policy '%s', expected one of fail, keep, placeholder or drop", value)
+}
+
+// Report collects the failures of a single ConfigDecryptor run.
+type Report struct {
+	// Values is the number of {cipher} values processed
+	Values   int
+	Failures []*DecryptError
+	// Keys counts the decrypted values by the fingerprint of the key which decrypted them
+	Keys map[string]int
+}
+
+// HasFailures reports whether any value failed to decrypt.
+func (r *Report) HasFailures() bool {
+	return r != nil && len(r.Failures) != 0
+}
+
+// Err returns the failures as a MultiError or nil if there are none.
+func (r *Report) Err() error {
+	if r == nil {
+		return nil
+	}
+	merr := merror.MultiError{}
+	for _, failure := range r.Failures {
+		merr.Add(failure)
+	}
+	return merr.Err()
+}
+
+func (r *Report) add(failure *DecryptError) {
+	r.Failures = append(r.Failures, failure)
+}
+
+func (r *Report) addKey(fingerprint string) {
+	if r.Keys == nil {
+		r.Keys = make(map[string]int)
+	}
+	r.Keys[fingerprint]++
+}
@@ -0,0 +1,51 @@
+package decryptor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+const defaultMask = "******"
+
+// Redactor replaces the decrypted plain text written by ConfigDecryptor.
+// The plain text is cleared after the call and must not be retained.
+type Redactor func(plainText []byte) string
+
+// FingerprintRedactor replaces the plain text with its SHA-256 fingerprint and length,
+// identical secrets get identical fingerprints e.g. <redacted:sha256:2c26b46b68ffc68f, len=3>.
+// If the key is not empty HMAC-SHA256 is used instead, so short secrets cannot be guessed from the fingerprint.
+func FingerprintRedactor(key []byte) Redactor {
+	return func(plainText []byte) string {
+		var sum []byte
+		algorithm := "sha256"
+		if len(key) != 0 {
+			algorithm = "hmac-sha256"
+			mac := hmac.New(sha256.New, key)
+			_, _ = mac.Write(plainText)
+			sum = mac.Sum(nil)
+		} else {
+			digest := sha256.Sum256(plainText)
+			sum = digest[:]
+		}
+		return fmt.Sprintf("<redacted:%s:%s, len=%d>", algorithm, hex.EncodeToString(sum[:8]), len(plainText))
+	}
+}
+
+// MaskRedactor replaces the plain text with a fixed mask, "******" if empty.
+func MaskRedactor(mask string) Redactor {
+	if mask == "" {
+		mask = defaultMask
+	}
+	return func([]byte) string {
+		return mask
+	}
+}
+
+// WithRedactor writes the redacted values instead of the plain text, the values are still decrypted to verify them.
+func WithRedactor(redactor Redactor) ConfigDecryptorOption {
+	return func(decryptor *ConfigDecryptor) {
+		decryptor.redactor = redactor
+	}
+}
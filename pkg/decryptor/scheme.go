@@ -0,0 +1,240 @@
+package decryptor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Scheme is a syntax of encrypted values like {cipher}... or ENC(...) together with the backend decrypting them.
+// Several schemes are processed in one pass by ConfigDecryptor.
+type Scheme interface {
+	// Match returns the start and end of the first encrypted value of the scheme in the text, nil if there is none.
+	Match(text string) []int
+	// Decrypt decrypts a matched value and returns the identifier of the key which decrypted it.
+	Decrypt(value string) ([]byte, string, error)
+}
+
+// SchemeConfig is the configuration of a scheme, e.g. an entry of the decryptors in the repository configuration.
+// The type entry selects the registered scheme factory.
+type SchemeConfig map[string]string
+
+// SchemeFactory creates a scheme from its configuration.
+type SchemeFactory func(config SchemeConfig) (Scheme, error)
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = make(map[string]SchemeFactory)
+)
+
+// RegisterScheme makes a scheme factory available by name, it panics if the name is registered twice.
+// Packages providing a scheme register it in their init function.
+func RegisterScheme(name string, factory SchemeFactory) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	if _, ok := schemes[name]; ok {
+		panic("decryptor: scheme " + name + " is registered twice")
+	}
+	schemes[name] = factory
+}
+
+// SchemeNames returns the sorted names of the registered schemes.
+func SchemeNames() []string {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	result := make([]string, 0, len(schemes))
+	for name := range schemes {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// NewScheme creates the scheme of the registered factory named by the type of the configuration.
+func NewScheme(config SchemeConfig) (Scheme, error) {
+	name := config["type"]
+	schemesMu.RLock()
+	factory, ok := schemes[name]
+	schemesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown decryptor type '%s', expected one of %s", name, strings.Join(SchemeNames(), ", "))
+	}
+	scheme, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("decryptor %s: %v", name, err)
+	}
+	return scheme, nil
+}
+
+// NewSchemes creates the schemes of the enabled configurations in the given order.
+func NewSchemes(configs []SchemeConfig) ([]Scheme, error) {
+	var result []Scheme
+	for _, config := range configs {
+		enabled, err := config.Bool("enabled", true)
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			continue
+		}
+		scheme, err := NewScheme(config)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, scheme)
+	}
+	return result, nil
+}
+
+// Validate rejects the entries which are none of the allowed names, type and enabled are always allowed.
+func (c SchemeConfig) Validate(allowed ...string) error {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+next:
+	for _, name := range names {
+		for _, a := range append(allowed, "type", "enabled") {
+			if name == a {
+				continue next
+			}
+		}
+		return fmt.Errorf("unknown entry '%s', expected %s", name, strings.Join(allowed, ", "))
+	}
+	return nil
+}
+
+// Get returns the entry or the default value if it is not set.
+func (c SchemeConfig) Get(name, defaultValue string) string {
+	if value, ok := c[name]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+// Bool returns the boolean entry or the default value if it is not set.
+func (c SchemeConfig) Bool(name string, defaultValue bool) (bool, error) {
+	value, ok := c[name]
+	if !ok {
+		return defaultValue, nil
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s '%s', expected true or false", name, value)
+	}
+	return result, nil
+}
+
+// Int returns the integer entry or the default value if it is not set.
+func (c SchemeConfig) Int(name string, defaultValue int) (int, error) {
+	value, ok := c[name]
+	if !ok {
+		return defaultValue, nil
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s '%s', expected a number", name, value)
+	}
+	return result, nil
+}
+
+type patternScheme struct {
+	pattern        *regexp.Regexp
+	valueDecryptor CipherDecryptor
+}
+
+func (s patternScheme) Match(text string) []int {
+	return s.pattern.FindStringIndex(text)
+}
+
+func (s patternScheme) Decrypt(value string) ([]byte, string, error) {
+	return s.valueDecryptor.DecryptValueWithKey(value)
+}
+
+// NewCipherScheme decrypts the {cipher} values with the value decryptor.
+func NewCipherScheme(valueDecryptor CipherDecryptor) Scheme {
+	return patternScheme{pattern: cipherPattern, valueDecryptor: valueDecryptor}
+}
+
+// NewMarkerScheme decrypts the values wrapped in prefix and suffix, like Jasypt's ENC(...), with the value decryptor.
+// The value including prefix and suffix is passed to the decryptor, the wrapped text is non-empty base64 or hex.
+func NewMarkerScheme(prefix, suffix string, valueDecryptor CipherDecryptor) Scheme {
+	pattern := regexp.MustCompile(regexp.QuoteMeta(prefix) + `[A-Za-z0-9+/=]+` + regexp.QuoteMeta(suffix))
+	return patternScheme{pattern: pattern, valueDecryptor: valueDecryptor}
+}
+
+type envelopeScheme struct {
+	valueDecryptor CipherDecryptor
+}
+
+// NewNonEnvelopeScheme decrypts the {cipher} values which are not Spring RSA envelopes, e.g. KMS ciphertext blobs.
+// It has to precede the RSA scheme, of two schemes matching at the same position the first one wins.
+func NewNonEnvelopeScheme(valueDecryptor CipherDecryptor) Scheme {
+	return envelopeScheme{valueDecryptor: valueDecryptor}
+}
+
+func (s envelopeScheme) Match(text string) []int {
+	offset := 0
+	for {
+		ns := cipherPattern.FindStringIndex(text[offset:])
+		if ns == nil {
+			return nil
+		}
+		if !IsRSAEnvelope(text[offset+ns[0] : offset+ns[1]]) {
+			return []int{offset + ns[0], offset + ns[1]}
+		}
+		offset += ns[1]
+	}
+}
+
+func (s envelopeScheme) Decrypt(value string) ([]byte, string, error) {
+	return s.valueDecryptor.DecryptValueWithKey(value)
+}
+
+func init() {
+	RegisterScheme("rsa", newRSAScheme)
+}
+
+// newRSAScheme creates the {cipher} scheme of the key source in key, further keys of the source are the keyring.
+func newRSAScheme(config SchemeConfig) (Scheme, error) {
+	if err := config.Validate("key", "salt", "algorithm", "strong", "key_alias"); err != nil {
+		return nil, err
+	}
+	if config["key"] == "" {
+		return nil, fmt.Errorf("key is required")
+	}
+	provider, err := ParseKeyProvider(config["key"])
+	if err != nil {
+		return nil, err
+	}
+	keys, err := provider.Keys()
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := ParseRsaAlgorithm(config.Get("algorithm", string(RsaAlgorithmDefault)))
+	if err != nil {
+		return nil, err
+	}
+	strong, err := config.Bool("strong", false)
+	if err != nil {
+		return nil, err
+	}
+	options := []ValueDecryptorOption{
+		WithSalt(config.Get("salt", DefaultSalt)),
+		WithAlgorithm(algorithm),
+		WithStrong(strong),
+		WithKeyAlias(config["key_alias"]),
+	}
+	if len(keys) > 1 {
+		options = append(options, WithKeyring(keys[1:]...))
+	}
+	valueDecryptor, err := NewValueDecryptor(keys[0], options...)
+	if err != nil {
+		return nil, err
+	}
+	return NewCipherScheme(valueDecryptor), nil
+}
@@ -0,0 +1,130 @@
+package decryptor
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// secretScheme resolves ${secret:name} from a map, as a third party scheme would.
+type secretScheme map[string]string
+
+func (s secretScheme) Match(text string) []int {
+	start := strings.Index(text, "${secret:")
+	if start == -1 {
+		return nil
+	}
+	end := strings.Index(text[start:], "}")
+	if end == -1 {
+		return nil
+	}
+	return []int{start, start + end + 1}
+}
+
+func (s secretScheme) Decrypt(value string) ([]byte, string, error) {
+	return []byte(s[value[len("${secret:"):len(value)-1]]), "secrets", nil
+}
+
+func init() {
+	RegisterScheme("test-secret", func(config SchemeConfig) (Scheme, error) {
+		if err := config.Validate("value"); err != nil {
+			return nil, err
+		}
+		return secretScheme{"db": config["value"]}, nil
+	})
+}
+
+func TestSchemes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schemes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "private.pem")
+	if err = ioutil.WriteFile(keyFile, []byte(privateKey), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		configs []SchemeConfig
+		err     string
+	}{
+		{name: "unknown type", configs: []SchemeConfig{{"type": "vault"}}, err: "unknown decryptor type 'vault', expected one of rsa, test-secret"},
+		{name: "unknown entry", configs: []SchemeConfig{{"type": "rsa", "file": keyFile}}, err: "decryptor rsa: unknown entry 'file', expected key, salt, algorithm, strong, key_alias"},
+		{name: "missing key", configs: []SchemeConfig{{"type": "rsa"}}, err: "decryptor rsa: key is required"},
+		{name: "enabled", configs: []SchemeConfig{{"type": "rsa", "enabled": "maybe"}}, err: "invalid enabled 'maybe', expected true or false"},
+		{name: "schemes", configs: []SchemeConfig{
+			{"type": "vault", "enabled": "false"},
+			{"type": "rsa", "key": "file://" + keyFile},
+			{"type": "test-secret", "value": "s3cr3t"},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			schemes, err := NewSchemes(tc.configs)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected error %q, actual %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("create schemes error: %v", err)
+			}
+			input := "a: ${secret:db} '{cipher}AQCE7t4KSgXRgRGRkJr4KhcS8Y5YsWzU07ac67ECLJPu6IbxkrkLn3mRl/FaTumJrbjX6+0gkG8e/TARjCj4tsVqx9Y8KK5yISaBHArKjyXDAJ71+nSsJAX/tcukONFGBqxYBkXH9OcXH8hoNagWWg/4pt3CwGw/wGgFU3dBLdvf8gu7S8YxCHWE5TSkUvxB/Gs/C5JLkklE3vz3ATYCnDTx1X8weQUxKeqOqe8AaElq8QkpVeJackkzsv2w6A8YydterEuELSjk5icLF0CKHlpD9x+emiprmaOADxjP526YinTlGnRsiDroaZ3avIURjUc+GCOt47i8grQIT1DmzUvailAMfsVgvnsSyKOO18VSqe11l9AKMnzEwqJ8cmHT3Kc='\n"
+			var output bytes.Buffer
+			report, err := NewSchemeDecryptor(schemes).DecryptWithReport(&output, strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("decrypt error: %v", err)
+			}
+			if output.String() != "a: s3cr3t 'foo'\n" || report.Keys["secrets"] != 1 || len(report.Keys) != 2 {
+				t.Errorf("Unexpected output %q %v", output.String(), report.Keys)
+			}
+		})
+	}
+}
+
+func TestNonEnvelopeScheme(t *testing.T) {
+	rsaValue := "{cipher}AQCE7t4KSgXRgRGRkJr4KhcS8Y5YsWzU07ac67ECLJPu6IbxkrkLn3mRl/FaTumJrbjX6+0gkG8e/TARjCj4tsVqx9Y8KK5yISaBHArKjyXDAJ71+nSsJAX/tcukONFGBqxYBkXH9OcXH8hoNagWWg/4pt3CwGw/wGgFU3dBLdvf8gu7S8YxCHWE5TSkUvxB/Gs/C5JLkklE3vz3ATYCnDTx1X8weQUxKeqOqe8AaElq8QkpVeJackkzsv2w6A8YydterEuELSjk5icLF0CKHlpD9x+emiprmaOADxjP526YinTlGnRsiDroaZ3avIURjUc+GCOt47i8grQIT1DmzUvailAMfsVgvnsSyKOO18VSqe11l9AKMnzEwqJ8cmHT3Kc="
+	configDecryptor := NewSchemeDecryptor([]Scheme{NewNonEnvelopeScheme(stubDecryptor("kms")), NewCipherScheme(stubDecryptor("rsa"))})
+	report, err := configDecryptor.Check(strings.NewReader("a: " + rsaValue + " {cipher}AQICAHh=\n"))
+	if err != nil {
+		t.Fatalf("check error: %v", err)
+	}
+	if report.Keys["kms"] != 1 || report.Keys["rsa"] != 1 {
+		t.Errorf("Unexpected keys %v", report.Keys)
+	}
+}
+
+func TestConfigDecryptorDecryptValue(t *testing.T) {
+	configDecryptor := NewSchemeDecryptor([]Scheme{NewCipherScheme(stubDecryptor("rsa")), secretScheme{"db": "s3cr3t"}})
+	for value, expected := range map[string]string{
+		"${secret:db}":     "s3cr3t",
+		"{cipher}AQ==":     "{cipher}AQ==",
+		"url ${secret:db}": "url ${secret:db}",
+		"plain":            "plain",
+	} {
+		actual, err := configDecryptor.DecryptValue(value)
+		if err != nil || actual != expected {
+			t.Errorf("%s: expected %q, actual %q %v", value, expected, actual, err)
+		}
+	}
+}
+
+func TestConfigDecryptorDecryptText(t *testing.T) {
+	configDecryptor := NewSchemeDecryptor([]Scheme{secretScheme{"db": "s3cr3t", "user": "app"}})
+	for text, expected := range map[string]string{
+		"${secret:db}":                          "s3cr3t",
+		"jdbc://${secret:user}@db?${secret:db}": "jdbc://app@db?s3cr3t",
+		"plain":                                 "plain",
+	} {
+		actual, err := configDecryptor.DecryptText(text)
+		if err != nil || actual != expected {
+			t.Errorf("%s: expected %q, actual %q %v", text, expected, actual, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package encryptor
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileName is the repository level configuration, it is looked up from the directory of the encrypted file upwards.
+const ConfigFileName = ".spring-config.yaml"
+
+// Config holds the rules which select the values to encrypt and the decryptors of the encrypted values.
+type Config struct {
+	EncryptionRules []Rule `yaml:"encryption_rules"`
+	// Decryptors configure the registered decryptor schemes, which are tried in the given order
+	Decryptors []decryptor.SchemeConfig `yaml:"decryptors"`
+}
+
+// Rule selects the property paths to encrypt in the files matching PathRegex.
+type Rule struct {
+	// PathRegex matches the slash separated file path relative to the config file, empty matches all files
+	PathRegex string `yaml:"path_regex"`
+	// EncryptedRegex matches the property paths to encrypt
+	EncryptedRegex string `yaml:"encrypted_regex"`
+	// Paths are the property paths or path globs to encrypt
+	Paths []string `yaml:"paths"`
+}
+
+// LoadConfig reads the configuration, unknown fields are rejected.
+func LoadConfig(r io.Reader) (*Config, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	config := &Config{}
+	if err := dec.Decode(config); err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "config parsing error")
+	}
+	return config, nil
+}
+
+// FindConfig returns the path of the nearest ConfigFileName in the directory or its parents, empty if there is none.
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		fileName := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(fileName); err == nil {
+			return fileName, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Rule returns the first rule matching the file path relative to the config file, nil if there is none.
+func (c *Config) Rule(relativePath string) (*Rule, error) {
+	for i := range c.EncryptionRules {
+		rule := &c.EncryptionRules[i]
+		if rule.PathRegex == "" {
+			return rule, nil
+		}
+		re, err := regexp.Compile(rule.PathRegex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid path_regex '%s'", rule.PathRegex)
+		}
+		if re.MatchString(filepath.ToSlash(relativePath)) {
+			return rule, nil
+		}
+	}
+	return nil, nil
+}
+
+// Matcher returns the function matching the property paths selected by the rule.
+func (r Rule) Matcher() (func(propertyPath string) bool, error) {
+	var re *regexp.Regexp
+	if r.EncryptedRegex != "" {
+		var err error
+		if re, err = regexp.Compile(r.EncryptedRegex); err != nil {
+			return nil, errors.Wrapf(err, "invalid encrypted_regex '%s'", r.EncryptedRegex)
+		}
+	}
+	for _, pattern := range r.Paths {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid path '%s'", pattern)
+		}
+	}
+	return func(propertyPath string) bool {
+		if re != nil && re.MatchString(propertyPath) {
+			return true
+		}
+		for _, pattern := range r.Paths {
+			if ok, _ := path.Match(pattern, propertyPath); ok {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
@@ -0,0 +1,181 @@
+package encryptor
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+	"github.com/pkg/errors"
+)
+
+// DefaultTag marks the plain text values which are encrypted by the Editor.
+const DefaultTag = "{encrypt}"
+
+type EditorOption func(editor *Editor)
+
+// ValueDecryptor decrypts a single {cipher} value, like decryptor.ConfigDecryptor with the schemes of the repository.
+type ValueDecryptor interface {
+	DecryptValue(value string) (string, error)
+}
+
+// Editor converts a configuration file with {cipher} values into an editable plain text document and back.
+// Values whose plain text did not change keep their original cipher text, so an edit produces a minimal diff.
+type Editor struct {
+	decryptor ValueDecryptor
+	encryptor *ValueEncryptor
+	tag       string
+}
+
+// NewEditor creates the editor, the encryptor may be nil if only Decrypt is used.
+func NewEditor(decryptor ValueDecryptor, encryptor *ValueEncryptor, options ...EditorOption) *Editor {
+	result := &Editor{decryptor: decryptor, encryptor: encryptor, tag: DefaultTag}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// WithTag sets the prefix marking the values to encrypt, DefaultTag by default.
+func WithTag(tag string) EditorOption {
+	return func(editor *Editor) {
+		editor.tag = tag
+	}
+}
+
+type editValue struct {
+	plainText string
+	// raw is the original value including its quotes
+	raw  string
+	used bool
+}
+
+// Decrypt replaces each {cipher} value of the content with its plain text prefixed with the tag.
+func (e *Editor) Decrypt(fileName string, content []byte) ([]byte, error) {
+	var sb strings.Builder
+	yaml := isYAML(fileName)
+	err := e.decryptValues(fileName, content, func(raw string, line properties.Line, value *editValue) error {
+		if value == nil {
+			sb.WriteString(raw)
+			return nil
+		}
+		quoted, err := quote(yaml, line.Quote, e.tag+value.plainText)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(raw[:line.Column-1])
+		sb.WriteString(quoted)
+		sb.WriteString(raw[line.Column-1+line.Length:])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(sb.String()), nil
+}
+
+// Encrypt encrypts the tagged values of the edited document produced by Decrypt from the original content.
+// A tagged value keeps its original cipher text if the plain text of a value with the same property path was not changed.
+// The number of newly encrypted values is returned.
+func (e *Editor) Encrypt(fileName string, original []byte, edited []byte) ([]byte, int, error) {
+	values := make(map[string][]*editValue)
+	err := e.decryptValues(fileName, original, func(raw string, line properties.Line, value *editValue) error {
+		if value != nil {
+			values[line.Path] = append(values[line.Path], value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var sb strings.Builder
+	yaml := isYAML(fileName)
+	count := 0
+	paths := properties.NewPathTracker()
+	for i, raw := range strings.SplitAfter(string(edited), "\n") {
+		line := paths.Next(raw)
+		if !line.HasValue || !strings.HasPrefix(line.Value, e.tag) {
+			sb.WriteString(raw)
+			continue
+		}
+		plainText := strings.TrimPrefix(line.Value, e.tag)
+		var replacement string
+		if value := unused(values[line.Path], plainText); value != nil {
+			value.used = true
+			replacement = value.raw
+		} else {
+			encrypted, err := e.encryptor.EncryptValue(plainText)
+			if err != nil {
+				return nil, 0, errors.Wrapf(err, "%s:%d property '%s'", fileName, i+1, line.Path)
+			}
+			replacement = encrypted
+			if line.Quote != 0 {
+				replacement = string(line.Quote) + encrypted + string(line.Quote)
+			} else if yaml {
+				replacement = "'" + encrypted + "'"
+			}
+			count++
+		}
+		sb.WriteString(raw[:line.Column-1])
+		sb.WriteString(replacement)
+		sb.WriteString(raw[line.Column-1+line.Length:])
+	}
+	return []byte(sb.String()), count, nil
+}
+
+// decryptValues calls fn for each line of the content with the decrypted value if the line value is a {cipher} value.
+func (e *Editor) decryptValues(fileName string, content []byte, fn func(raw string, line properties.Line, value *editValue) error) error {
+	paths := properties.NewPathTracker()
+	for i, raw := range strings.SplitAfter(string(content), "\n") {
+		line := paths.Next(raw)
+		if !line.HasValue || !decryptor.IsCipherValue(line.Value) {
+			if err := fn(raw, line, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		newError := func(err error) error {
+			return &decryptor.DecryptError{File: fileName, Line: i + 1, Column: line.Column, Path: line.Path, Err: err}
+		}
+		plainText, err := e.decryptor.DecryptValue(line.Value)
+		if err != nil {
+			return newError(err)
+		}
+		value := &editValue{plainText: plainText, raw: raw[line.Column-1 : line.Column-1+line.Length]}
+		if err = fn(raw, line, value); err != nil {
+			return newError(err)
+		}
+	}
+	return nil
+}
+
+func unused(values []*editValue, plainText string) *editValue {
+	for _, value := range values {
+		if !value.used && value.plainText == plainText {
+			return value
+		}
+	}
+	return nil
+}
+
+// quote formats the value so that the PathTracker reads it back unchanged.
+func quote(yaml bool, quote byte, value string) (string, error) {
+	if strings.ContainsAny(value, "\r\n") {
+		return "", errors.New("multi-line values cannot be edited")
+	}
+	if !yaml {
+		if strings.TrimRight(value, " \t") != value {
+			return "", errors.New("values with trailing whitespace cannot be edited")
+		}
+		return value, nil
+	}
+	if quote == '"' {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`, nil
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'", nil
+}
+
+func isYAML(fileName string) bool {
+	return strings.ToLower(filepath.Ext(fileName)) != ".properties"
+}
@@ -0,0 +1,128 @@
+package encryptor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+)
+
+func TestEdit(t *testing.T) {
+	privateKey, publicKey := generateKey(t)
+	valueEncryptor, err := NewValueEncryptor(publicKey)
+	if err != nil {
+		t.Fatalf("create value encryptor error: %v", err)
+	}
+	valueDecryptor, err := decryptor.NewValueDecryptor(privateKey)
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	encrypt := func(value string) string {
+		encrypted, err := valueEncryptor.EncryptValue(value)
+		if err != nil {
+			t.Fatalf("encrypt error: %v", err)
+		}
+		return encrypted
+	}
+	user, password, token := encrypt("foo"), encrypt("it's"), encrypt(`a"b`)
+	original := "db:\n  user: " + user + "\n  password: '" + password + "' # comment\n  token: \"" + token + "\"\n  port: 5432\n"
+
+	editor := NewEditor(valueDecryptor, valueEncryptor)
+	plainText, err := editor.Decrypt("application.yml", []byte(original))
+	if err != nil {
+		t.Fatalf("decrypt error: %v", err)
+	}
+	expected := "db:\n  user: '{encrypt}foo'\n  password: '{encrypt}it''s' # comment\n  token: \"{encrypt}a\\\"b\"\n  port: 5432\n"
+	if string(plainText) != expected {
+		t.Fatalf("Decrypted content differs: expected %q, actual %q", expected, plainText)
+	}
+
+	tt := []struct {
+		name      string
+		edited    string
+		count     int
+		expected  func(actual string) bool
+		decrypted string
+	}{
+		{
+			name:      "Unchanged",
+			edited:    expected,
+			expected:  func(actual string) bool { return actual == original },
+			decrypted: expected,
+		},
+		{
+			name:   "Changed value",
+			edited: strings.Replace(expected, "{encrypt}foo", "{encrypt}bar", 1),
+			count:  1,
+			expected: func(actual string) bool {
+				return !strings.Contains(actual, user) && strings.Contains(actual, password) && strings.Contains(actual, token)
+			},
+			decrypted: strings.Replace(expected, "{encrypt}foo", "{encrypt}bar", 1),
+		},
+		{
+			name:   "Added value",
+			edited: expected + "api:\n  key: {encrypt}secret\n",
+			count:  1,
+			expected: func(actual string) bool {
+				return strings.HasPrefix(actual, original+"api:\n  key: '{cipher}")
+			},
+			decrypted: expected + "api:\n  key: '{encrypt}secret'\n",
+		},
+		{
+			name:   "Reordered values",
+			edited: "db:\n  password: '{encrypt}it''s'\n  user: '{encrypt}foo'\n",
+			expected: func(actual string) bool {
+				return actual == "db:\n  password: '"+password+"'\n  user: "+user+"\n"
+			},
+			decrypted: "db:\n  password: '{encrypt}it''s'\n  user: '{encrypt}foo'\n",
+		},
+		{
+			name:     "Untagged value",
+			edited:   strings.Replace(expected, "{encrypt}foo", "foo", 1),
+			expected: func(actual string) bool { return strings.HasPrefix(actual, "db:\n  user: 'foo'\n") },
+		},
+	}
+	for _, tc := range tt {
+		actual, count, err := editor.Encrypt("application.yml", []byte(original), []byte(tc.edited))
+		if err != nil {
+			t.Fatalf("%s: encrypt error: %v", tc.name, err)
+		}
+		if count != tc.count {
+			t.Errorf("%s: count differs: expected %d, actual %d", tc.name, tc.count, count)
+		}
+		if !tc.expected(string(actual)) {
+			t.Errorf("%s: unexpected content %q", tc.name, actual)
+		}
+		decrypted, err := editor.Decrypt("application.yml", actual)
+		if err != nil {
+			t.Fatalf("%s: decrypt error: %v", tc.name, err)
+		}
+		if tc.decrypted != "" && string(decrypted) != tc.decrypted {
+			t.Errorf("%s: decrypted content differs: expected %q, actual %q", tc.name, tc.decrypted, decrypted)
+		}
+	}
+}
+
+func TestEditProperties(t *testing.T) {
+	privateKey, publicKey := generateKey(t)
+	valueEncryptor, err := NewValueEncryptor(publicKey)
+	if err != nil {
+		t.Fatalf("create value encryptor error: %v", err)
+	}
+	valueDecryptor, err := decryptor.NewValueDecryptor(privateKey)
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	encrypted, err := valueEncryptor.EncryptValue("foo ")
+	if err != nil {
+		t.Fatalf("encrypt error: %v", err)
+	}
+	editor := NewEditor(valueDecryptor, valueEncryptor, WithTag("ENC:"))
+	if _, err = editor.Decrypt("application.properties", []byte("a.b="+encrypted+"\n")); err == nil {
+		t.Errorf("Expected trailing whitespace error")
+	}
+	actual, count, err := editor.Encrypt("application.properties", nil, []byte("a.b=ENC:bar\n"))
+	if err != nil || count != 1 || !strings.HasPrefix(string(actual), "a.b={cipher}") {
+		t.Errorf("Unexpected result %q %d %v", actual, count, err)
+	}
+}
@@ -0,0 +1,208 @@
+package encryptor
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const secretLength = 16
+
+type ValueEncryptorOption func(encryptor *ValueEncryptor) error
+
+// ValueEncryptor encrypts values in the format of Spring's RsaSecretEncryptor.
+type ValueEncryptor struct {
+	publicKey *rsa.PublicKey
+	salt      []byte
+	keyAlias  string
+	algorithm decryptor.RsaAlgorithm
+	strong    bool
+	random    io.Reader
+}
+
+// NewValueEncryptor creates the encryptor from a public key or from a private key.
+func NewValueEncryptor(key []byte, options ...ValueEncryptorOption) (*ValueEncryptor, error) {
+	publicKey, err := ParsePublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	result := &ValueEncryptor{publicKey: publicKey, algorithm: decryptor.RsaAlgorithmDefault, random: rand.Reader}
+	if err := WithSalt(decryptor.DefaultSalt)(result); err != nil {
+		return nil, err
+	}
+	for _, option := range options {
+		if err = option(result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ParsePublicKey parses a PKIX or PKCS1 public key, PEM or DER encoded.
+// The public key of a private key accepted by decryptor.ParsePrivateKey is returned as well.
+func ParsePublicKey(key []byte) (*rsa.PublicKey, error) {
+	der := key
+	if block, _ := pem.Decode(key); block != nil {
+		der = block.Bytes
+	}
+	if parsed, err := x509.ParsePKIXPublicKey(der); err == nil {
+		publicKey, ok := parsed.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not an RSA key")
+		}
+		return publicKey, nil
+	}
+	if publicKey, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return publicKey, nil
+	}
+	privateKey, err := decryptor.ParsePrivateKey(key)
+	if err != nil {
+		return nil, errors.New("public key should be a PEM or plain PKIX or PKCS1 public key or a private key")
+	}
+	return &privateKey.PublicKey, nil
+}
+
+func WithSalt(salt string) ValueEncryptorOption {
+	return func(encryptor *ValueEncryptor) error {
+		if saltBytes, err := hex.DecodeString(salt); err != nil {
+			return fmt.Errorf("salt '%s' cannot be hex decoded: %v", salt, err)
+		} else {
+			encryptor.salt = saltBytes
+		}
+		return nil
+	}
+}
+
+// WithKeyAlias adds the {key:alias} prefix to the encrypted values.
+func WithKeyAlias(alias string) ValueEncryptorOption {
+	return func(encryptor *ValueEncryptor) error {
+		encryptor.keyAlias = alias
+		return nil
+	}
+}
+
+// WithAlgorithm sets the RSA padding of the session key.
+func WithAlgorithm(algorithm decryptor.RsaAlgorithm) ValueEncryptorOption {
+	return func(encryptor *ValueEncryptor) error {
+		encryptor.algorithm = algorithm
+		return nil
+	}
+}
+
+// WithStrong enables the AES/GCM encryption of the value used by Spring's strong mode instead of AES/CBC.
+func WithStrong(strong bool) ValueEncryptorOption {
+	return func(encryptor *ValueEncryptor) error {
+		encryptor.strong = strong
+		return nil
+	}
+}
+
+// WithRandom sets the source of randomness, intended for tests.
+func WithRandom(random io.Reader) ValueEncryptorOption {
+	return func(encryptor *ValueEncryptor) error {
+		encryptor.random = random
+		return nil
+	}
+}
+
+// EncryptValue encrypts the plain text into a {cipher} value.
+func (e ValueEncryptor) EncryptValue(plainText string) (string, error) {
+	return e.EncryptBytes([]byte(plainText))
+}
+
+// EncryptBytes encrypts the plain text into a {cipher} value.
+func (e ValueEncryptor) EncryptBytes(plainText []byte) (string, error) {
+	secret := make([]byte, secretLength)
+	if _, err := io.ReadFull(e.random, secret); err != nil {
+		return "", errors.Wrap(err, "session key generation error")
+	}
+	password := []byte(hex.EncodeToString(secret))
+	key := pbkdf2.Key(password, e.salt, 1024, 32, sha1.New)
+	defer decryptor.Zero(secret)
+	defer decryptor.Zero(password)
+	defer decryptor.Zero(key)
+
+	var (
+		encryptedSecret []byte
+		err             error
+	)
+	if e.algorithm == decryptor.RsaAlgorithmOAEP {
+		encryptedSecret, err = rsa.EncryptOAEP(sha1.New(), e.random, e.publicKey, secret, nil)
+	} else {
+		encryptedSecret, err = rsa.EncryptPKCS1v15(e.random, e.publicKey, secret)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "session key encryption error")
+	}
+
+	var encrypted []byte
+	if e.strong {
+		encrypted, err = e.encryptGCM(key, plainText)
+	} else {
+		encrypted, err = e.encryptCBC(key, plainText)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, uint16(len(encryptedSecret)))
+	buf.Write(encryptedSecret)
+	buf.Write(encrypted)
+
+	prefix := decryptor.CipherPrefix
+	if e.keyAlias != "" {
+		prefix += "{key:" + e.keyAlias + "}"
+	}
+	return prefix + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func (e ValueEncryptor) encryptCBC(key, plainText []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padding := aes.BlockSize - len(plainText)%aes.BlockSize
+	result := make([]byte, aes.BlockSize+len(plainText)+padding)
+	iv := result[:aes.BlockSize]
+	if _, err = io.ReadFull(e.random, iv); err != nil {
+		return nil, errors.Wrap(err, "initialization vector generation error")
+	}
+	padded := result[aes.BlockSize:]
+	copy(padded, plainText)
+	for i := len(plainText); i < len(padded); i++ {
+		padded[i] = byte(padding)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(padded, padded)
+	return result, nil
+}
+
+func (e ValueEncryptor) encryptGCM(key, plainText []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aes.BlockSize)
+	if _, err = io.ReadFull(e.random, nonce); err != nil {
+		return nil, errors.Wrap(err, "nonce generation error")
+	}
+	return gcm.Seal(nonce, nonce, plainText, nil), nil
+}
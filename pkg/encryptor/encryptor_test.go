@@ -0,0 +1,201 @@
+package encryptor
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+)
+
+func generateKey(t *testing.T) (privateKey []byte, publicKey []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate key error: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal public key error: %v", err)
+	}
+	privateKey = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicKey = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	return privateKey, publicKey
+}
+
+func TestEncryptValue(t *testing.T) {
+	privateKey, publicKey := generateKey(t)
+	tt := []struct {
+		name      string
+		salt      string
+		algorithm decryptor.RsaAlgorithm
+		strong    bool
+		value     string
+	}{
+		{name: "Default", salt: decryptor.DefaultSalt, algorithm: decryptor.RsaAlgorithmDefault, value: "foo"},
+		{name: "Empty value", salt: decryptor.DefaultSalt, algorithm: decryptor.RsaAlgorithmDefault, value: ""},
+		{name: "Block size value", salt: "cafe", algorithm: decryptor.RsaAlgorithmDefault, value: "1234567890abcdef"},
+		{name: "OAEP", salt: decryptor.DefaultSalt, algorithm: decryptor.RsaAlgorithmOAEP, value: "foo"},
+		{name: "Strong", salt: decryptor.DefaultSalt, algorithm: decryptor.RsaAlgorithmDefault, strong: true, value: "foo bar"},
+		{name: "OAEP strong", salt: "beef", algorithm: decryptor.RsaAlgorithmOAEP, strong: true, value: "ünïcode"},
+	}
+	for _, tc := range tt {
+		valueEncryptor, err := NewValueEncryptor(publicKey, WithSalt(tc.salt), WithAlgorithm(tc.algorithm), WithStrong(tc.strong), WithKeyAlias("new"))
+		if err != nil {
+			t.Fatalf("%s: create value encryptor error: %v", tc.name, err)
+		}
+		encrypted, err := valueEncryptor.EncryptValue(tc.value)
+		if err != nil {
+			t.Fatalf("%s: encrypt error: %v", tc.name, err)
+		}
+		if !strings.HasPrefix(encrypted, "{cipher}{key:new}") {
+			t.Errorf("%s: unexpected prefix %s", tc.name, encrypted)
+		}
+		valueDecryptor, err := decryptor.NewValueDecryptor(privateKey, decryptor.WithSalt(tc.salt), decryptor.WithAlgorithm(tc.algorithm), decryptor.WithStrong(tc.strong))
+		if err != nil {
+			t.Fatalf("%s: create value decryptor error: %v", tc.name, err)
+		}
+		actual, err := valueDecryptor.DecryptValue(encrypted)
+		if err != nil {
+			t.Fatalf("%s: decrypt error: %v", tc.name, err)
+		}
+		if actual != tc.value {
+			t.Errorf("%s: values differ: expected %v, actual %v", tc.name, tc.value, actual)
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	oldPrivateKey, oldPublicKey := generateKey(t)
+	olderPrivateKey, olderPublicKey := generateKey(t)
+	newPrivateKey, newPublicKey := generateKey(t)
+
+	encryptValue := func(key []byte, value string) string {
+		e, err := NewValueEncryptor(key)
+		if err != nil {
+			t.Fatalf("create value encryptor error: %v", err)
+		}
+		encrypted, err := e.EncryptValue(value)
+		if err != nil {
+			t.Fatalf("encrypt error: %v", err)
+		}
+		return encrypted
+	}
+	newDecryptor := func(key []byte) *decryptor.ValueDecryptor {
+		d, err := decryptor.NewValueDecryptor(key)
+		if err != nil {
+			t.Fatalf("create value decryptor error: %v", err)
+		}
+		return d
+	}
+	content := "db:\n  password: '" + encryptValue(oldPublicKey, "foo") + "' # keep me\n  user: \"" + encryptValue(olderPublicKey, "bar") + "\"\n  port: 5432\n"
+
+	encryptor, err := NewValueEncryptor(newPublicKey)
+	if err != nil {
+		t.Fatalf("create value encryptor error: %v", err)
+	}
+	rotator := NewRotator([]*decryptor.ValueDecryptor{newDecryptor(oldPrivateKey), newDecryptor(olderPrivateKey)}, encryptor, newDecryptor(newPrivateKey))
+	rotated, count, err := rotator.Rotate(content)
+	if err != nil {
+		t.Fatalf("rotate error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Count differs: expected 2, actual %d", count)
+	}
+	if !strings.Contains(rotated, "' # keep me\n  user: \"") || !strings.HasSuffix(rotated, "\"\n  port: 5432\n") {
+		t.Errorf("Unexpected rotated content %s", rotated)
+	}
+	actual, err := decryptor.NewConfigDecryptor(newDecryptor(newPrivateKey)).Check(strings.NewReader(rotated))
+	if err != nil || actual.HasFailures() {
+		t.Errorf("Rotated values cannot be decrypted: %v %v", err, actual.Err())
+	}
+
+	// verification with a key not matching the new public key
+	rotator = NewRotator([]*decryptor.ValueDecryptor{newDecryptor(oldPrivateKey), newDecryptor(olderPrivateKey)}, encryptor, newDecryptor(oldPrivateKey))
+	if _, _, err = rotator.Rotate(content); !errors.Is(err, ErrVerification) {
+		t.Errorf("Expected verification error, actual %v", err)
+	}
+}
+
+func TestRotateFiles(t *testing.T) {
+	oldPrivateKey, oldPublicKey := generateKey(t)
+	newPrivateKey, newPublicKey := generateKey(t)
+	oldEncryptor, err := NewValueEncryptor(oldPublicKey)
+	if err != nil {
+		t.Fatalf("create value encryptor error: %v", err)
+	}
+	newEncryptor, err := NewValueEncryptor(newPublicKey)
+	if err != nil {
+		t.Fatalf("create value encryptor error: %v", err)
+	}
+	oldDecryptor, err := decryptor.NewValueDecryptor(oldPrivateKey)
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	newDecryptor, err := decryptor.NewValueDecryptor(newPrivateKey)
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	encrypted, err := oldEncryptor.EncryptValue("foo")
+	if err != nil {
+		t.Fatalf("encrypt error: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatalf("temp dir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	first, second, plain := filepath.Join(dir, "a.yml"), filepath.Join(dir, "b.yml"), filepath.Join(dir, "c.yml")
+	content := "password: '" + encrypted + "'\n"
+	for name, data := range map[string]string{first: content, second: "token: '{cipher}AQ=='\n", plain: "port: 1\n"} {
+		if err = ioutil.WriteFile(name, []byte(data), 0640); err != nil {
+			t.Fatalf("write file error: %v", err)
+		}
+	}
+	rotator := NewRotator([]*decryptor.ValueDecryptor{oldDecryptor}, newEncryptor, newDecryptor)
+
+	// a failure in the second file leaves the first one untouched
+	if _, err = rotator.RotateFiles([]string{first, second}); err == nil || !strings.HasPrefix(err.Error(), second+": ") {
+		t.Fatalf("Expected error of the second file, actual %v", err)
+	}
+	if actual, _ := ioutil.ReadFile(first); string(actual) != content {
+		t.Errorf("First file was changed: %q", actual)
+	}
+
+	rotated, err := rotator.RotateFiles([]string{first, plain})
+	if err != nil {
+		t.Fatalf("rotate error: %v", err)
+	}
+	if len(rotated) != 1 || rotated[0].Path != first || rotated[0].Count != 1 {
+		t.Fatalf("Unexpected rotated files %+v", rotated)
+	}
+	if err = WriteRotatedFiles(rotated); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	actual, err := ioutil.ReadFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := decryptor.NewConfigDecryptor(newDecryptor).Check(strings.NewReader(string(actual)))
+	if err != nil || report.HasFailures() || report.Values != 1 {
+		t.Errorf("Rotated values cannot be decrypted: %v %v", err, report.Err())
+	}
+	info, err := os.Stat(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("Expected kept permission, actual %v", info.Mode())
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil || len(entries) != 3 {
+		t.Errorf("Expected no temporary files, actual %d %v", len(entries), err)
+	}
+}
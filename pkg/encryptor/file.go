@@ -0,0 +1,165 @@
+package encryptor
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+	"github.com/pkg/errors"
+)
+
+// FileEncryptor encrypts the plain text values of a YAML, properties or JSON file selected by their property paths.
+// Values which are already {cipher} values are left unchanged, so encrypting a file again is a no-op.
+type FileEncryptor struct {
+	encryptor *ValueEncryptor
+	match     func(path string) bool
+}
+
+func NewFileEncryptor(encryptor *ValueEncryptor, match func(path string) bool) *FileEncryptor {
+	return &FileEncryptor{encryptor: encryptor, match: match}
+}
+
+// Encrypt returns the content with the matching values encrypted and the number of encrypted values.
+// Only the values are replaced, the formatting and comments of the file are kept.
+func (f *FileEncryptor) Encrypt(fileName string, content []byte) ([]byte, int, error) {
+	if strings.ToLower(filepath.Ext(fileName)) == ".json" {
+		return f.encryptJSON(content)
+	}
+	var sb strings.Builder
+	yaml := isYAML(fileName)
+	count := 0
+	paths := properties.NewPathTracker()
+	for i, raw := range strings.SplitAfter(string(content), "\n") {
+		line := paths.Next(raw)
+		if !line.HasValue || !encryptable(line) || !f.match(line.Path) {
+			sb.WriteString(raw)
+			continue
+		}
+		encrypted, err := f.encryptor.EncryptValue(line.Value)
+		if err != nil {
+			return nil, 0, errors.Wrapf(err, "%s:%d property '%s'", fileName, i+1, line.Path)
+		}
+		if line.Quote != 0 {
+			encrypted = string(line.Quote) + encrypted + string(line.Quote)
+		} else if yaml {
+			encrypted = "'" + encrypted + "'"
+		}
+		sb.WriteString(raw[:line.Column-1])
+		sb.WriteString(encrypted)
+		sb.WriteString(raw[line.Column-1+line.Length:])
+		count++
+	}
+	return []byte(sb.String()), count, nil
+}
+
+// encryptable reports whether the line value is a plain text scalar.
+func encryptable(line properties.Line) bool {
+	if line.Value == "" || strings.HasPrefix(line.Value, decryptor.CipherPrefix) {
+		return false
+	}
+	// block scalars, flow collections, anchors, aliases, tags and multi-line properties are left as they are
+	if line.Quote == 0 && (strings.ContainsAny(line.Value[:1], "|>[{&*!") || strings.HasSuffix(line.Value, `\`)) {
+		return false
+	}
+	return true
+}
+
+type jsonFrame struct {
+	array  bool
+	key    string
+	index  int
+	hasKey bool
+}
+
+func (f *FileEncryptor) encryptJSON(content []byte) ([]byte, int, error) {
+	var (
+		buf    bytes.Buffer
+		stack  []*jsonFrame
+		count  int
+		offset int
+		last   int
+	)
+	// a completed value moves its parent to the next key or index
+	next := func() {
+		if n := len(stack); n != 0 {
+			if stack[n-1].array {
+				stack[n-1].index++
+			} else {
+				stack[n-1].hasKey = false
+			}
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "JSON parsing error")
+		}
+		start, end := offset, int(dec.InputOffset())
+		offset = end
+		for start < end && strings.IndexByte(" \t\r\n,:", content[start]) != -1 {
+			start++
+		}
+
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				stack = append(stack, &jsonFrame{array: t == '['})
+			default:
+				stack = stack[:len(stack)-1]
+				next()
+			}
+			continue
+		case string:
+			if n := len(stack); n != 0 && !stack[n-1].array && !stack[n-1].hasKey {
+				stack[n-1].key = t
+				stack[n-1].hasKey = true
+				continue
+			}
+		}
+
+		value := string(content[start:end])
+		if s, ok := token.(string); ok {
+			value = s
+		}
+		if token != nil && value != "" && !strings.HasPrefix(value, decryptor.CipherPrefix) && f.match(jsonPath(stack)) {
+			encrypted, err := f.encryptor.EncryptValue(value)
+			if err != nil {
+				return nil, 0, errors.Wrapf(err, "property '%s'", jsonPath(stack))
+			}
+			quoted, _ := json.Marshal(encrypted)
+			buf.Write(content[last:start])
+			buf.Write(quoted)
+			last = end
+			count++
+		}
+		next()
+	}
+	buf.Write(content[last:])
+	return buf.Bytes(), count, nil
+}
+
+func jsonPath(stack []*jsonFrame) string {
+	var sb strings.Builder
+	for _, frame := range stack {
+		if frame.array {
+			sb.WriteString("[" + strconv.Itoa(frame.index) + "]")
+			continue
+		}
+		if sb.Len() != 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(frame.key)
+	}
+	return sb.String()
+}
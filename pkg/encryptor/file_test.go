@@ -0,0 +1,116 @@
+package encryptor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+)
+
+func TestEncryptFile(t *testing.T) {
+	privateKey, publicKey := generateKey(t)
+	valueEncryptor, err := NewValueEncryptor(publicKey)
+	if err != nil {
+		t.Fatalf("create value encryptor error: %v", err)
+	}
+	valueDecryptor, err := decryptor.NewValueDecryptor(privateKey)
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	match, err := Rule{EncryptedRegex: "password|secret", Paths: []string{"db.*"}}.Matcher()
+	if err != nil {
+		t.Fatalf("create matcher error: %v", err)
+	}
+	fileEncryptor := NewFileEncryptor(valueEncryptor, match)
+
+	tt := []struct {
+		name      string
+		fileName  string
+		input     string
+		count     int
+		decrypted string
+	}{
+		{
+			name:      "YAML",
+			fileName:  "application.yml",
+			input:     "db:\n  user: admin # comment\n  port: \"5432\"\napi:\n  secret: |\n    block\n  list:\n    - password: p1\nother: value\n",
+			count:     3,
+			decrypted: "db:\n  user: 'admin' # comment\n  port: \"5432\"\napi:\n  secret: |\n    block\n  list:\n    - password: 'p1'\nother: value\n",
+		},
+		{
+			name:      "Properties",
+			fileName:  "application.properties",
+			input:     "db.password=pw\nuser=x\n",
+			count:     1,
+			decrypted: "db.password=pw\nuser=x\n",
+		},
+		{
+			name:      "JSON",
+			fileName:  "application.json",
+			input:     "{\n  \"db\": {\"user\": \"admin\", \"port\": 5432, \"none\": null},\n  \"api\": [{\"secret\": \"s3cr3t\"}],\n  \"user\": \"x\"\n}\n",
+			count:     3,
+			decrypted: "{\n  \"db\": {\"user\": \"admin\", \"port\": \"5432\", \"none\": null},\n  \"api\": [{\"secret\": \"s3cr3t\"}],\n  \"user\": \"x\"\n}\n",
+		},
+	}
+	for _, tc := range tt {
+		encrypted, count, err := fileEncryptor.Encrypt(tc.fileName, []byte(tc.input))
+		if err != nil {
+			t.Fatalf("%s: encrypt error: %v", tc.name, err)
+		}
+		if count != tc.count {
+			t.Errorf("%s: count differs: expected %d, actual %d", tc.name, tc.count, count)
+		}
+		again, count, err := fileEncryptor.Encrypt(tc.fileName, encrypted)
+		if err != nil || count != 0 || !bytes.Equal(again, encrypted) {
+			t.Errorf("%s: encryption is not idempotent: %d %v", tc.name, count, err)
+		}
+		var decrypted bytes.Buffer
+		if err = decryptor.NewConfigDecryptor(valueDecryptor).Decrypt(&decrypted, bytes.NewReader(encrypted)); err != nil {
+			t.Fatalf("%s: decrypt error: %v", tc.name, err)
+		}
+		if decrypted.String() != tc.decrypted {
+			t.Errorf("%s: decrypted content differs: expected %q, actual %q", tc.name, tc.decrypted, decrypted.String())
+		}
+	}
+}
+
+func TestConfigRule(t *testing.T) {
+	config, err := LoadConfig(strings.NewReader(`
+encryption_rules:
+  - path_regex: '^prod/'
+    paths: ['spring.datasource.password']
+  - encrypted_regex: 'secret'
+`))
+	if err != nil {
+		t.Fatalf("load config error: %v", err)
+	}
+	tt := []struct {
+		name         string
+		fileName     string
+		propertyPath string
+		expected     bool
+	}{
+		{name: "Path rule", fileName: "prod/application.yml", propertyPath: "spring.datasource.password", expected: true},
+		{name: "Path rule no match", fileName: "prod/application.yml", propertyPath: "api.secret", expected: false},
+		{name: "Default rule", fileName: "dev/application.yml", propertyPath: "api.secret", expected: true},
+		{name: "Default rule no match", fileName: "dev/application.yml", propertyPath: "spring.datasource.password", expected: false},
+	}
+	for _, tc := range tt {
+		rule, err := config.Rule(tc.fileName)
+		if err != nil || rule == nil {
+			t.Fatalf("%s: rule error: %v %v", tc.name, rule, err)
+		}
+		match, err := rule.Matcher()
+		if err != nil {
+			t.Fatalf("%s: matcher error: %v", tc.name, err)
+		}
+		if actual := match(tc.propertyPath); actual != tc.expected {
+			t.Errorf("%s: match differs: expected %v, actual %v", tc.name, tc.expected, actual)
+		}
+	}
+
+	if _, err = LoadConfig(strings.NewReader("encryption_rule: []\n")); err == nil {
+		t.Errorf("Expected unknown field error")
+	}
+}
@@ -0,0 +1,168 @@
+package encryptor
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	merror "github.com/grepplabs/spring-config-decryptor/pkg/errors"
+	perrors "github.com/pkg/errors"
+)
+
+// ErrVerification is returned when a re-encrypted value does not decrypt to the original plain text.
+var ErrVerification = errors.New("re-encrypted value verification failed")
+
+// Rotator re-encrypts {cipher} values for a new key.
+type Rotator struct {
+	decryptors []*decryptor.ValueDecryptor
+	encryptor  *ValueEncryptor
+	verifier   *decryptor.ValueDecryptor
+}
+
+// NewRotator creates a rotator which decrypts the values with any of the old keys and encrypts them with the new one.
+// When the verifier is set, each new value is decrypted with it and compared with the original plain text.
+func NewRotator(decryptors []*decryptor.ValueDecryptor, encryptor *ValueEncryptor, verifier *decryptor.ValueDecryptor) *Rotator {
+	return &Rotator{
+		decryptors: decryptors,
+		encryptor:  encryptor,
+		verifier:   verifier,
+	}
+}
+
+// Rotate re-encrypts all {cipher} values of the content and returns the number of rotated values.
+// Only the cipher texts are replaced, the rest of the content is left untouched.
+func (r *Rotator) Rotate(content string) (string, int, error) {
+	count := 0
+	result, err := decryptor.ReplaceCipherValues(content, func(value string) (string, error) {
+		rotated, err := r.RotateValue(value)
+		if err != nil {
+			return "", err
+		}
+		count++
+		return rotated, nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return result, count, nil
+}
+
+// RotateValue re-encrypts a single {cipher} value.
+func (r *Rotator) RotateValue(value string) (string, error) {
+	plainText, err := r.decrypt(value)
+	if err != nil {
+		return "", err
+	}
+	defer decryptor.Zero(plainText)
+
+	rotated, err := r.encryptor.EncryptBytes(plainText)
+	if err != nil {
+		return "", err
+	}
+	if r.verifier != nil {
+		verified, err := r.verifier.DecryptValueBytes(rotated)
+		defer decryptor.Zero(verified)
+		if err != nil {
+			return "", merror.MultiError{ErrVerification, err}
+		}
+		if subtle.ConstantTimeCompare(plainText, verified) != 1 {
+			return "", ErrVerification
+		}
+	}
+	return rotated, nil
+}
+
+func (r *Rotator) decrypt(value string) ([]byte, error) {
+	merr := merror.MultiError{}
+	for _, d := range r.decryptors {
+		plainText, err := d.DecryptValueBytes(value)
+		if err == nil {
+			return plainText, nil
+		}
+		merr.Add(err)
+	}
+	if len(merr) == 0 {
+		return nil, errors.New("no decryption key")
+	}
+	return nil, merr
+}
+
+// RotatedFile is a file with re-encrypted values.
+type RotatedFile struct {
+	Path    string
+	Content []byte
+	Count   int
+}
+
+// RotateFiles re-encrypts the {cipher} values of the files in memory and returns the files with rotated values.
+// Nothing is written, so a value which fails in any file leaves all files unchanged.
+func (r *Rotator) RotateFiles(paths []string) ([]RotatedFile, error) {
+	var result []RotatedFile
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rotated, count, err := r.Rotate(string(content))
+		if err != nil {
+			return nil, perrors.Wrapf(err, "%s", path)
+		}
+		if count != 0 {
+			result = append(result, RotatedFile{Path: path, Content: []byte(rotated), Count: count})
+		}
+	}
+	return result, nil
+}
+
+// WriteRotatedFiles replaces the files with their rotated content keeping their permissions.
+// All contents are written to temporary files next to the originals first, which are then renamed over them,
+// so a failed write or a crash never leaves a truncated file.
+func WriteRotatedFiles(files []RotatedFile) error {
+	tempFiles := make([]string, 0, len(files))
+	// the renamed temporary files do not exist anymore
+	defer func() {
+		for _, tempFile := range tempFiles {
+			_ = os.Remove(tempFile)
+		}
+	}()
+	for _, file := range files {
+		tempFile, err := writeTempFile(file.Path, file.Content)
+		if err != nil {
+			return perrors.Wrapf(err, "%s", file.Path)
+		}
+		tempFiles = append(tempFiles, tempFile)
+	}
+	for i, file := range files {
+		if err := os.Rename(tempFiles[i], file.Path); err != nil {
+			return perrors.Wrapf(err, "%s", file.Path)
+		}
+	}
+	return nil
+}
+
+// writeTempFile writes the content to a temporary file in the directory of the file with the permissions of the file.
+func writeTempFile(path string, content []byte) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Chmod(info.Mode())
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -2,6 +2,7 @@ package errors
 
 import (
 	"bytes"
+	stderrors "errors"
 	"fmt"
 )
 
@@ -46,3 +47,23 @@ func (es MultiError) Err() error {
 	}
 	return es
 }
+
+// Is reports whether any of the contained errors matches the target.
+func (es MultiError) Is(target error) bool {
+	for _, err := range es {
+		if stderrors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first contained error that matches the target and sets target to that error value.
+func (es MultiError) As(target interface{}) bool {
+	for _, err := range es {
+		if stderrors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package gitfilter
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/encryptor"
+	"github.com/pkg/errors"
+)
+
+// Name is the filter and diff driver name used in .gitattributes.
+const Name = "springcipher"
+
+type FilterOption func(filter *Filter)
+
+// Filter implements the git smudge and clean filters and the textconv diff driver for files with {cipher} values.
+type Filter struct {
+	editor  *encryptor.Editor
+	schemes []decryptor.Scheme
+	dir     string
+}
+
+// NewFilter creates the filter, the textconv diff driver decrypts the values of the schemes.
+func NewFilter(editor *encryptor.Editor, schemes []decryptor.Scheme, options ...FilterOption) *Filter {
+	result := &Filter{editor: editor, schemes: schemes}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// WithDir sets the git working tree, the current directory by default.
+func WithDir(dir string) FilterOption {
+	return func(filter *Filter) {
+		filter.dir = dir
+	}
+}
+
+// Smudge decrypts the content checked out from the repository, the decrypted values are prefixed with the editor tag.
+func (f *Filter) Smudge(path string, content []byte) ([]byte, error) {
+	return f.editor.Decrypt(path, content)
+}
+
+// Clean encrypts the tagged values of the working tree content.
+// Values whose plain text did not change keep the cipher text of the staged version, so the blob stays the same.
+func (f *Filter) Clean(path string, content []byte) ([]byte, error) {
+	staged, err := f.staged(path)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, _, err := f.editor.Encrypt(path, staged, content)
+	return encrypted, err
+}
+
+// Textconv writes the content with the encrypted values decrypted, values which cannot be decrypted are kept.
+func (f *Filter) Textconv(w io.Writer, r io.Reader) error {
+	return decryptor.NewSchemeDecryptor(f.schemes, decryptor.WithErrorPolicy(decryptor.KeepCipherText)).Decrypt(w, r)
+}
+
+// staged returns the content of the file in the index, nil if the path is not in the index.
+func (f *Filter) staged(path string) ([]byte, error) {
+	name := filepath.ToSlash(path)
+	indexed, err := f.git("--literal-pathspecs", "ls-files", "--cached", "--", name)
+	if err != nil {
+		return nil, err
+	}
+	if len(indexed) == 0 {
+		// new file
+		return nil, nil
+	}
+	return f.git("cat-file", "blob", ":"+name)
+}
+
+// git runs the git command in the working tree, the error includes the stderr of git.
+func (f *Filter) git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = f.dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "git %s error %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
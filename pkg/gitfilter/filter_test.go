@@ -0,0 +1,124 @@
+package gitfilter
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/encryptor"
+)
+
+func newTestFilter(t *testing.T, dir string) (*Filter, *encryptor.ValueEncryptor) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate key error: %v", err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	valueDecryptor, err := decryptor.NewValueDecryptor(privateKey)
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	valueEncryptor, err := encryptor.NewValueEncryptor(privateKey)
+	if err != nil {
+		t.Fatalf("create value encryptor error: %v", err)
+	}
+	schemes := []decryptor.Scheme{decryptor.NewCipherScheme(valueDecryptor)}
+	return NewFilter(encryptor.NewEditor(decryptor.NewSchemeDecryptor(schemes), valueEncryptor), schemes, WithDir(dir)), valueEncryptor
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v error: %v %s", args, err, out)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "gitfilter")
+	if err != nil {
+		t.Fatalf("temp dir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filter, valueEncryptor := newTestFilter(t, dir)
+	user, err := valueEncryptor.EncryptValue("foo")
+	if err != nil {
+		t.Fatalf("encrypt error: %v", err)
+	}
+	password, err := valueEncryptor.EncryptValue("bar")
+	if err != nil {
+		t.Fatalf("encrypt error: %v", err)
+	}
+	staged := "db:\n  user: '" + user + "'\n  password: '" + password + "'\n"
+
+	git(t, dir, "init", "-q")
+	if err = ioutil.WriteFile(filepath.Join(dir, "application.yml"), []byte(staged), 0600); err != nil {
+		t.Fatalf("write file error: %v", err)
+	}
+	git(t, dir, "add", "application.yml")
+
+	smudged, err := filter.Smudge("application.yml", []byte(staged))
+	if err != nil {
+		t.Fatalf("smudge error: %v", err)
+	}
+	if expected := "db:\n  user: '{encrypt}foo'\n  password: '{encrypt}bar'\n"; string(smudged) != expected {
+		t.Errorf("Smudged content differs: expected %q, actual %q", expected, smudged)
+	}
+
+	cleaned, err := filter.Clean("application.yml", smudged)
+	if err != nil {
+		t.Fatalf("clean error: %v", err)
+	}
+	if string(cleaned) != staged {
+		t.Errorf("Unchanged content was re-encrypted: %q", cleaned)
+	}
+
+	cleaned, err = filter.Clean("application.yml", bytes.Replace(smudged, []byte("{encrypt}bar"), []byte("{encrypt}baz"), 1))
+	if err != nil {
+		t.Fatalf("clean error: %v", err)
+	}
+	if !strings.HasPrefix(string(cleaned), "db:\n  user: '"+user+"'\n") || strings.Contains(string(cleaned), password) {
+		t.Errorf("Unexpected cleaned content: %q", cleaned)
+	}
+
+	// files which are not staged yet are encrypted completely
+	cleaned, err = filter.Clean("new.yml", smudged)
+	if err != nil {
+		t.Fatalf("clean error: %v", err)
+	}
+	if strings.Contains(string(cleaned), "{encrypt}") || strings.Contains(string(cleaned), user) {
+		t.Errorf("Unexpected cleaned content: %q", cleaned)
+	}
+
+	// other git failures are not mistaken for a new file
+	notRepository, err := ioutil.TempDir("", "gitfilter")
+	if err != nil {
+		t.Fatalf("temp dir error: %v", err)
+	}
+	defer os.RemoveAll(notRepository)
+	other, _ := newTestFilter(t, notRepository)
+	if _, err = other.Clean("application.yml", smudged); err == nil || !strings.Contains(err.Error(), "not a git repository") {
+		t.Errorf("Expected git error, actual %v", err)
+	}
+
+	var textconv bytes.Buffer
+	if err = filter.Textconv(&textconv, strings.NewReader(staged+"other: '{cipher}AQ=='\n")); err != nil {
+		t.Fatalf("textconv error: %v", err)
+	}
+	if expected := "db:\n  user: 'foo'\n  password: 'bar'\nother: '{cipher}AQ=='\n"; textconv.String() != expected {
+		t.Errorf("Textconv content differs: expected %q, actual %q", expected, textconv.String())
+	}
+}
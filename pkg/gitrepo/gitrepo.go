@@ -0,0 +1,192 @@
+// Package gitrepo emulates the git backend of Spring Cloud Config Server on a local repository. The files are read
+// from the git objects of the label, no checkout is needed.
+package gitrepo
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/configdata"
+	"github.com/grepplabs/spring-config-decryptor/pkg/configserver"
+	"github.com/pkg/errors"
+)
+
+// DefaultLabel is the label used when none is requested, master is tried if it does not exist.
+const DefaultLabel = "main"
+
+type RepositoryOption func(*Repository) error
+
+// Repository is a local git repository laid out like a Config Server git backend.
+type Repository struct {
+	dir          string
+	searchPaths  []string
+	defaultLabel string
+}
+
+// NewRepository creates the repository of the git directory or work tree.
+func NewRepository(dir string, options ...RepositoryOption) (*Repository, error) {
+	repository := &Repository{dir: dir}
+	for _, option := range options {
+		if err := option(repository); err != nil {
+			return nil, err
+		}
+	}
+	return repository, nil
+}
+
+// WithSearchPaths sets the search-paths of the Config Server, they may contain {application}, {profile} and {label}
+// placeholders and * wildcards. The root of the repository is always searched first.
+func WithSearchPaths(searchPaths ...string) RepositoryOption {
+	return func(repository *Repository) error {
+		for _, searchPath := range searchPaths {
+			if searchPath = strings.TrimSpace(searchPath); searchPath != "" {
+				repository.searchPaths = append(repository.searchPaths, strings.Trim(searchPath, "/"))
+			}
+		}
+		return nil
+	}
+}
+
+// WithDefaultLabel sets the label used when none is requested.
+func WithDefaultLabel(label string) RepositoryOption {
+	return func(repository *Repository) error {
+		repository.defaultLabel = label
+		return nil
+	}
+}
+
+// Environment returns the environment the Config Server returns for the application, the comma separated profiles
+// and the label, a branch, tag or commit. As in the Config Server (_) in the label stands for a slash.
+// The version of the environment is the commit id, the values are not decrypted.
+func (r *Repository) Environment(application, profile, label string) (*configserver.Environment, error) {
+	commit, label, err := r.resolveLabel(label)
+	if err != nil {
+		return nil, err
+	}
+	files, dirs, err := r.listTree(commit)
+	if err != nil {
+		return nil, err
+	}
+	profiles := configdata.SplitProfiles(profile)
+	locations := []string{""}
+	for _, searchPath := range r.searchPaths {
+		for _, location := range expandSearchPath(searchPath, application, profiles, label) {
+			locations = append(locations, matchDirs(location, dirs)...)
+		}
+	}
+	readFile := func(name string) ([]byte, error) {
+		if !files[name] {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		return r.git("cat-file", "blob", commit+":"+name)
+	}
+	loader, err := configdata.NewLoader(readFile, configdata.WithLocations(locations...))
+	if err != nil {
+		return nil, err
+	}
+	env, err := loader.Load(application, profiles)
+	if err != nil {
+		return nil, err
+	}
+	env.Label = label
+	env.Version = commit
+	return env, nil
+}
+
+// resolveLabel returns the commit id of the label or of the remote branch with the name of the label.
+func (r *Repository) resolveLabel(label string) (string, string, error) {
+	candidates := []string{strings.ReplaceAll(label, "(_)", "/")}
+	if label == "" {
+		candidates = []string{r.defaultLabel}
+		if r.defaultLabel == "" {
+			candidates = []string{DefaultLabel, "master"}
+		}
+	}
+	for _, candidate := range candidates {
+		// a label like --output=x would be an option of git
+		if strings.HasPrefix(candidate, "-") {
+			return "", "", errors.Errorf("invalid label: %s", candidate)
+		}
+		for _, ref := range []string{candidate, "origin/" + candidate} {
+			out, err := r.git("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+			if err == nil {
+				return strings.TrimSpace(string(out)), candidate, nil
+			}
+		}
+	}
+	return "", "", errors.Errorf("no such label: %s", strings.Join(candidates, ", "))
+}
+
+// listTree returns the files and directories of the commit.
+func (r *Repository) listTree(commit string) (map[string]bool, []string, error) {
+	out, err := r.git("ls-tree", "-r", "-t", "-z", "--full-tree", commit)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := make(map[string]bool)
+	var dirs []string
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// <mode> SP <type> SP <object> TAB <path>
+		i := strings.IndexByte(entry, '\t')
+		if i == -1 {
+			continue
+		}
+		fields := strings.Fields(entry[:i])
+		switch {
+		case len(fields) == 3 && fields[1] == "blob":
+			files[entry[i+1:]] = true
+		case len(fields) == 3 && fields[1] == "tree":
+			dirs = append(dirs, entry[i+1:])
+		}
+	}
+	return files, dirs, nil
+}
+
+func (r *Repository) git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "git %s error %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// expandSearchPath replaces the placeholders of the search path, {profile} yields a location per profile.
+func expandSearchPath(searchPath, application string, profiles []string, label string) []string {
+	if application == "" {
+		application = configdata.DefaultName
+	}
+	searchPath = strings.ReplaceAll(searchPath, "{application}", application)
+	searchPath = strings.ReplaceAll(searchPath, "{label}", label)
+	if !strings.Contains(searchPath, "{profile}") {
+		return []string{searchPath}
+	}
+	if len(profiles) == 0 {
+		profiles = []string{configdata.DefaultProfile}
+	}
+	var result []string
+	for _, profile := range profiles {
+		result = append(result, strings.ReplaceAll(searchPath, "{profile}", profile))
+	}
+	return result
+}
+
+// matchDirs returns the directories matching the location with * wildcards, the location itself if it has none.
+func matchDirs(location string, dirs []string) []string {
+	if !strings.Contains(location, "*") {
+		return []string{location}
+	}
+	var result []string
+	for _, dir := range dirs {
+		if ok, _ := path.Match(location, dir); ok {
+			result = append(result, dir)
+		}
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package gitrepo
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v error: %v %s", args, err, out)
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		fileName := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "gitrepo")
+	if err != nil {
+		t.Fatalf("temp dir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	git(t, dir, "init", "-q")
+	git(t, dir, "config", "user.email", "test@example.com")
+	git(t, dir, "config", "user.name", "test")
+	git(t, dir, "checkout", "-q", "-b", "main")
+	writeFiles(t, dir, map[string]string{
+		"application.yml":           "a: root\nb: root\nc: root\n",
+		"orders/orders.yml":         "b: orders\n",
+		"orders/orders-dev.yml":     "c: orders-dev\n",
+		"shared/eu/application.yml": "d: eu\n",
+		"other/other.yml":           "b: other\n",
+	})
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-q", "-m", "v1")
+	git(t, dir, "tag", "v1")
+	writeFiles(t, dir, map[string]string{"orders/orders.yml": "b: orders-v2\n"})
+	git(t, dir, "commit", "-q", "-am", "v2")
+	git(t, dir, "checkout", "-q", "-b", "feature/x")
+	writeFiles(t, dir, map[string]string{"orders/orders.yml": "b: feature\n"})
+	git(t, dir, "commit", "-q", "-am", "feature")
+	// the work tree is not read
+	writeFiles(t, dir, map[string]string{"orders/orders.yml": "b: work-tree\n"})
+
+	repository, err := NewRepository(dir, WithSearchPaths("{application}", "shared/*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		profile  string
+		label    string
+		expected properties.Properties
+		sources  []string
+	}{
+		{
+			name:     "default label",
+			profile:  "dev",
+			expected: properties.Properties{"a": "root", "b": "orders-v2", "c": "orders-dev", "d": "eu"},
+			sources:  []string{"orders/orders-dev.yml", "shared/eu/application.yml", "orders/orders.yml", "application.yml"},
+		},
+		{
+			name:     "tag",
+			profile:  "default",
+			label:    "v1",
+			expected: properties.Properties{"a": "root", "b": "orders", "c": "root", "d": "eu"},
+			sources:  []string{"shared/eu/application.yml", "orders/orders.yml", "application.yml"},
+		},
+		{
+			name:     "branch with slash",
+			label:    "feature(_)x",
+			expected: properties.Properties{"a": "root", "b": "feature", "c": "root", "d": "eu"},
+			sources:  []string{"shared/eu/application.yml", "orders/orders.yml", "application.yml"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			env, err := repository.Environment("orders", tc.profile, tc.label)
+			if err != nil {
+				t.Fatalf("environment error: %v", err)
+			}
+			var sources []string
+			for _, ps := range env.PropertySources {
+				sources = append(sources, ps.Name)
+			}
+			if !reflect.DeepEqual(sources, tc.sources) {
+				t.Errorf("Sources differ: expected %v, actual %v", tc.sources, sources)
+			}
+			if actual := env.Properties(); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Properties differ: expected %v, actual %v", tc.expected, actual)
+			}
+			if len(env.Version) != 40 {
+				t.Errorf("Unexpected version %s", env.Version)
+			}
+		})
+	}
+
+	if _, err = repository.Environment("orders", "dev", "--output=x"); err == nil || err.Error() != "invalid label: --output=x" {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if _, err = repository.Environment("orders", "dev", "v9"); err == nil || err.Error() != "no such label: v9" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+// Package jasypt decrypts the ENC(...) values of jasypt-spring-boot.
+package jasypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// DefaultPrefix and DefaultSuffix wrap the encrypted values, as jasypt-spring-boot does.
+	DefaultPrefix = "ENC("
+	DefaultSuffix = ")"
+	// DefaultAlgorithm is the algorithm of jasypt-spring-boot 3.
+	DefaultAlgorithm = "PBEWITHHMACSHA512ANDAES_256"
+	// LegacyAlgorithm is the algorithm of jasypt-spring-boot 1 and 2.
+	LegacyAlgorithm = "PBEWithMD5AndDES"
+	// DefaultIterations is the default number of key obtention iterations.
+	DefaultIterations = 1000
+)
+
+var pbes2Pattern = regexp.MustCompile(`^PBEWITHHMAC(SHA1|SHA224|SHA256|SHA384|SHA512)ANDAES_(128|256)$`)
+
+var pbes2Hashes = map[string]func() hash.Hash{
+	"SHA1":   sha1.New,
+	"SHA224": sha256.New224,
+	"SHA256": sha256.New,
+	"SHA384": sha512.New384,
+	"SHA512": sha512.New,
+}
+
+// algorithm derives the key and the IV from the password and the salt and creates the block cipher.
+type algorithm struct {
+	name      string
+	blockSize int
+	// randomIV reports whether the IV is stored in the message after the salt, as by RandomIvGenerator
+	randomIV bool
+	cipher   func(password, salt []byte, iterations int) (cipher.Block, []byte, error)
+}
+
+func parseAlgorithm(name string) (*algorithm, error) {
+	upper := strings.ToUpper(name)
+	if upper == strings.ToUpper(LegacyAlgorithm) {
+		return &algorithm{name: LegacyAlgorithm, blockSize: des.BlockSize, cipher: pbes1MD5DES}, nil
+	}
+	m := pbes2Pattern.FindStringSubmatch(upper)
+	if m == nil {
+		return nil, fmt.Errorf("unsupported jasypt algorithm '%s', expected %s or PBEWITHHMACSHA<n>ANDAES_<bits>", name, LegacyAlgorithm)
+	}
+	newHash := pbes2Hashes[m[1]]
+	keyLength, _ := strconv.Atoi(m[2])
+	return &algorithm{
+		name:      upper,
+		blockSize: aes.BlockSize,
+		randomIV:  true,
+		cipher: func(password, salt []byte, iterations int) (cipher.Block, []byte, error) {
+			key := pbkdf2.Key(password, salt, iterations, keyLength/8, newHash)
+			defer decryptor.Zero(key)
+			block, err := aes.NewCipher(key)
+			return block, nil, err
+		},
+	}, nil
+}
+
+// pbes1MD5DES is PBKDF1 with MD5 of PKCS #5, the first half of the derived bytes is the DES key, the second the IV.
+func pbes1MD5DES(password, salt []byte, iterations int) (cipher.Block, []byte, error) {
+	derived := append(append([]byte(nil), password...), salt...)
+	for i := 0; i < iterations; i++ {
+		sum := md5.Sum(derived)
+		decryptor.Zero(derived)
+		derived = sum[:]
+	}
+	defer decryptor.Zero(derived[:des.BlockSize])
+	block, err := des.NewCipher(derived[:des.BlockSize])
+	return block, derived[des.BlockSize:], err
+}
+
+type ValueDecryptorOption func(decryptor *ValueDecryptor) error
+
+// ValueDecryptor decrypts values encrypted by the StandardPBEStringEncryptor of jasypt.
+// The base64 message is the salt, the IV if a RandomIvGenerator was used and the cipher text.
+type ValueDecryptor struct {
+	password   []byte
+	algorithm  *algorithm
+	iterations int
+	prefix     string
+	suffix     string
+}
+
+func NewValueDecryptor(password []byte, options ...ValueDecryptorOption) (*ValueDecryptor, error) {
+	if len(password) == 0 {
+		return nil, errors.New("jasypt password is required")
+	}
+	algorithm, _ := parseAlgorithm(DefaultAlgorithm)
+	result := &ValueDecryptor{
+		password:   password,
+		algorithm:  algorithm,
+		iterations: DefaultIterations,
+		prefix:     DefaultPrefix,
+		suffix:     DefaultSuffix,
+	}
+	for _, option := range options {
+		if err := option(result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// WithAlgorithm sets the PBE algorithm, PBEWithMD5AndDES or PBEWITHHMACSHA<n>ANDAES_<bits>.
+func WithAlgorithm(name string) ValueDecryptorOption {
+	return func(decryptor *ValueDecryptor) error {
+		algorithm, err := parseAlgorithm(name)
+		if err != nil {
+			return err
+		}
+		decryptor.algorithm = algorithm
+		return nil
+	}
+}
+
+// WithIterations sets the number of key obtention iterations.
+func WithIterations(iterations int) ValueDecryptorOption {
+	return func(decryptor *ValueDecryptor) error {
+		if iterations <= 0 {
+			return fmt.Errorf("iterations %d must be positive", iterations)
+		}
+		decryptor.iterations = iterations
+		return nil
+	}
+}
+
+// WithRandomIV overrides whether the message contains the IV after the salt, as written with RandomIvGenerator.
+// By default it does for the AES algorithms, which require it. PBEWithMD5AndDES derives the IV from the password
+// and skips the IV of the message.
+func WithRandomIV(randomIV bool) ValueDecryptorOption {
+	return func(decryptor *ValueDecryptor) error {
+		algorithm := *decryptor.algorithm
+		algorithm.randomIV = randomIV
+		decryptor.algorithm = &algorithm
+		return nil
+	}
+}
+
+// WithWrapper sets the prefix and suffix of the encrypted values, ENC( and ) by default.
+func WithWrapper(prefix, suffix string) ValueDecryptorOption {
+	return func(decryptor *ValueDecryptor) error {
+		if prefix == "" || suffix == "" {
+			return errors.New("jasypt prefix and suffix must not be empty")
+		}
+		decryptor.prefix = prefix
+		decryptor.suffix = suffix
+		return nil
+	}
+}
+
+// Prefix returns the prefix of the encrypted values.
+func (d ValueDecryptor) Prefix() string {
+	return d.prefix
+}
+
+// Suffix returns the suffix of the encrypted values.
+func (d ValueDecryptor) Suffix() string {
+	return d.suffix
+}
+
+func (d ValueDecryptor) DecryptValue(value string) (string, error) {
+	plainText, _, err := d.DecryptValueWithKey(value)
+	if err != nil {
+		return "", err
+	}
+	defer decryptor.Zero(plainText)
+	return string(plainText), nil
+}
+
+// DecryptValueWithKey decrypts the wrapped value and returns jasypt: followed by the algorithm as key identifier,
+// empty if the value is not wrapped.
+func (d ValueDecryptor) DecryptValueWithKey(value string) ([]byte, string, error) {
+	if len(value) < len(d.prefix)+len(d.suffix) || !strings.HasPrefix(value, d.prefix) || !strings.HasSuffix(value, d.suffix) {
+		return []byte(value), "", nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value[len(d.prefix) : len(value)-len(d.suffix)])
+	if err != nil {
+		// do not include the value, the error messages may end up in logs
+		return nil, "", fmt.Errorf("%w: %v", decryptor.ErrInvalidBase64, err)
+	}
+	plainText, err := d.decrypt(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return plainText, "jasypt:" + d.algorithm.name, nil
+}
+
+func (d ValueDecryptor) decrypt(data []byte) ([]byte, error) {
+	blockSize := d.algorithm.blockSize
+	headerSize := blockSize
+	if d.algorithm.randomIV {
+		headerSize += blockSize
+	}
+	if len(data) < headerSize+blockSize || (len(data)-headerSize)%blockSize != 0 {
+		return nil, fmt.Errorf("%w to read salt, IV and cipher text", decryptor.ErrDataTooShort)
+	}
+	salt, cipherText := data[:blockSize], data[headerSize:]
+	block, iv, err := d.algorithm.cipher(d.password, salt, d.iterations)
+	if err != nil {
+		return nil, err
+	}
+	if iv == nil {
+		if !d.algorithm.randomIV {
+			return nil, errors.New("the AES algorithms require the IV in the message")
+		}
+		iv = data[blockSize:headerSize]
+	}
+	plainText := make([]byte, len(cipherText))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plainText, cipherText)
+	unpadded, err := unpad(plainText, blockSize)
+	if err != nil {
+		decryptor.Zero(plainText)
+		return nil, err
+	}
+	return unpadded, nil
+}
+
+func unpad(src []byte, blockSize int) ([]byte, error) {
+	length := len(src)
+	unpadding := int(src[length-1])
+	if unpadding == 0 || unpadding > blockSize {
+		return nil, decryptor.ErrInvalidPadding
+	}
+	for _, b := range src[length-unpadding:] {
+		if int(b) != unpadding {
+			return nil, decryptor.ErrInvalidPadding
+		}
+	}
+	return src[:length-unpadding], nil
+}
@@ -0,0 +1,85 @@
+package jasypt
+
+import (
+	"testing"
+)
+
+func TestDecryptValue(t *testing.T) {
+	// the messages are encrypted with openssl enc using keys derived by Python hashlib
+	tests := []struct {
+		name     string
+		value    string
+		options  []ValueDecryptorOption
+		expected string
+		key      string
+		err      string
+	}{
+		{
+			name:     "default algorithm",
+			value:    "ENC(AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh9UvMaVUZ3aZsodbtCOpXfb)",
+			expected: "jdbc-password",
+			key:      "jasypt:PBEWITHHMACSHA512ANDAES_256",
+		},
+		{
+			name:     "legacy algorithm",
+			value:    "ENC(AAECAwQFBgcTdhfOG3bcvica4HKC7vq8)",
+			options:  []ValueDecryptorOption{WithAlgorithm("PBEWITHMD5ANDDES")},
+			expected: "jdbc-password",
+			key:      "jasypt:PBEWithMD5AndDES",
+		},
+		{
+			name:     "custom wrapper",
+			value:    "SECRET[AAECAwQFBgcTdhfOG3bcvica4HKC7vq8]",
+			options:  []ValueDecryptorOption{WithAlgorithm(LegacyAlgorithm), WithWrapper("SECRET[", "]")},
+			expected: "jdbc-password",
+			key:      "jasypt:PBEWithMD5AndDES",
+		},
+		{
+			name:     "not wrapped",
+			value:    "ENC(AAECAwQFBgcTdhfOG3bcvica4HKC7vq8",
+			expected: "ENC(AAECAwQFBgcTdhfOG3bcvica4HKC7vq8",
+		},
+		{
+			name:  "wrong iterations",
+			value: "ENC(AAECAwQFBgcTdhfOG3bcvica4HKC7vq8)",
+			// the padding of a wrong key is valid by chance with probability 1/256
+			options: []ValueDecryptorOption{WithAlgorithm(LegacyAlgorithm), WithIterations(1001)},
+			err:     "invalid padding",
+		},
+		{
+			name:  "too short",
+			value: "ENC(AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=)",
+			err:   "data too short to read salt, IV and cipher text",
+		},
+		{
+			name:  "base64",
+			value: "ENC(???)",
+			err:   "value cannot be base64 decoded: illegal base64 data at input byte 0",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			valueDecryptor, err := NewValueDecryptor([]byte("secret-password"), tc.options...)
+			if err != nil {
+				t.Fatalf("create decryptor error: %v", err)
+			}
+			plainText, key, err := valueDecryptor.DecryptValueWithKey(tc.value)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected error %q, actual %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decrypt error: %v", err)
+			}
+			if string(plainText) != tc.expected || key != tc.key {
+				t.Errorf("Unexpected result %q %q", plainText, key)
+			}
+		})
+	}
+
+	if _, err := NewValueDecryptor([]byte("x"), WithAlgorithm("PBEWITHSHA1ANDRC4_128")); err == nil {
+		t.Errorf("Expected unsupported algorithm error")
+	}
+}
@@ -0,0 +1,65 @@
+package jasypt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+)
+
+// EnvPassword is the environment variable of the password, as in jasypt-spring-boot.
+const EnvPassword = "JASYPT_ENCRYPTOR_PASSWORD"
+
+func init() {
+	decryptor.RegisterScheme("jasypt", newScheme)
+}
+
+// newScheme creates the scheme of the wrapped values, the password is read from password_file or password_env.
+func newScheme(config decryptor.SchemeConfig) (decryptor.Scheme, error) {
+	if err := config.Validate("password_file", "password_env", "algorithm", "iterations", "random_iv", "prefix", "suffix"); err != nil {
+		return nil, err
+	}
+	password, err := readPassword(config)
+	if err != nil {
+		return nil, err
+	}
+	iterations, err := config.Int("iterations", DefaultIterations)
+	if err != nil {
+		return nil, err
+	}
+	options := []ValueDecryptorOption{
+		WithAlgorithm(config.Get("algorithm", DefaultAlgorithm)),
+		WithIterations(iterations),
+		WithWrapper(config.Get("prefix", DefaultPrefix), config.Get("suffix", DefaultSuffix)),
+	}
+	if _, ok := config["random_iv"]; ok {
+		randomIV, err := config.Bool("random_iv", true)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, WithRandomIV(randomIV))
+	}
+	valueDecryptor, err := NewValueDecryptor(password, options...)
+	if err != nil {
+		return nil, err
+	}
+	return decryptor.NewMarkerScheme(valueDecryptor.Prefix(), valueDecryptor.Suffix(), valueDecryptor), nil
+}
+
+func readPassword(config decryptor.SchemeConfig) ([]byte, error) {
+	if name := config["password_file"]; name != "" {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("password file reading error: %v", err)
+		}
+		return []byte(strings.TrimRight(string(data), "\r\n")), nil
+	}
+	name := config.Get("password_env", EnvPassword)
+	password := os.Getenv(name)
+	if password == "" {
+		return nil, fmt.Errorf("missing password, provide it in the env variable %s or in password_file", name)
+	}
+	return []byte(password), nil
+}
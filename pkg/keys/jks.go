@@ -0,0 +1,103 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	jksMagic         = 0xfeedfeed
+	jksVersion       = 2
+	jksPrivateKeyTag = 1
+	// jksDigestWhitener is mixed into the integrity digest of Sun's JKS keystore
+	jksDigestWhitener = "Mighty Aphrodite"
+)
+
+var oidJKSKeyProtector = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 42, 2, 17, 1, 1}
+
+// EncodeJKS encodes the private key and its certificate as Java keystore (JKS) entry with the alias.
+// The key and the keystore are protected with the same password. JKS lower cases the alias.
+func EncodeJKS(key *rsa.PrivateKey, certificate []byte, alias string, password []byte, created time.Time) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("keystore password is required")
+	}
+	// JKS uses the password as big-endian UTF-16 without terminator
+	passwordBytes := utf16BE(string(password))
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal private key")
+	}
+	protectedKey, err := jksProtect(der, passwordBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeUint32 := func(v uint32) {
+		_ = binary.Write(&buf, binary.BigEndian, v)
+	}
+	writeUTF := func(s string) {
+		_ = binary.Write(&buf, binary.BigEndian, uint16(len(s)))
+		buf.WriteString(s)
+	}
+	writeUint32(jksMagic)
+	writeUint32(jksVersion)
+	writeUint32(1)
+	writeUint32(jksPrivateKeyTag)
+	writeUTF(strings.ToLower(alias))
+	_ = binary.Write(&buf, binary.BigEndian, created.UnixNano()/int64(time.Millisecond))
+	writeUint32(uint32(len(protectedKey)))
+	buf.Write(protectedKey)
+	writeUint32(1)
+	writeUTF("X.509")
+	writeUint32(uint32(len(certificate)))
+	buf.Write(certificate)
+
+	digest := sha1.New()
+	_, _ = digest.Write(passwordBytes)
+	_, _ = digest.Write([]byte(jksDigestWhitener))
+	_, _ = digest.Write(buf.Bytes())
+	buf.Write(digest.Sum(nil))
+	return buf.Bytes(), nil
+}
+
+// jksProtect encrypts the PKCS8 private key with Sun's proprietary key protector:
+// salt, the key XORed with a SHA-1 key stream of the password and a SHA-1 checksum of the key.
+func jksProtect(plainKey []byte, passwordBytes []byte) ([]byte, error) {
+	salt := make([]byte, sha1.Size)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "generate salt")
+	}
+	encrypted := append(append([]byte{}, salt...), jksKeyStream(plainKey, passwordBytes, salt)...)
+	checksum := sha1.Sum(append(append([]byte{}, passwordBytes...), plainKey...))
+	encrypted = append(encrypted, checksum[:]...)
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidJKSKeyProtector, Parameters: asn1.NullRawValue},
+		EncryptedData: encrypted,
+	})
+}
+
+// jksKeyStream XORs the data with the key stream SHA-1(password || previous digest) starting with the salt,
+// applying it twice restores the data.
+func jksKeyStream(data []byte, passwordBytes []byte, salt []byte) []byte {
+	result := make([]byte, len(data))
+	digest := salt
+	for offset := 0; offset < len(data); offset += sha1.Size {
+		sum := sha1.Sum(append(append([]byte{}, passwordBytes...), digest...))
+		digest = sum[:]
+		for i := 0; i < sha1.Size && offset+i < len(data); i++ {
+			result[offset+i] = data[offset+i] ^ digest[i]
+		}
+	}
+	return result
+}
@@ -0,0 +1,143 @@
+// Package keys generates RSA key pairs and encodes them as PEM, PKCS#12 or JKS for the Config Server.
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/pkg/errors"
+)
+
+// MinBits is the smallest accepted RSA modulus size.
+const MinBits = 2048
+
+const (
+	pemPrivateKeyPKCS1     = "RSA PRIVATE KEY"
+	pemPrivateKeyPKCS8     = "PRIVATE KEY"
+	pemEncryptedPrivateKey = "ENCRYPTED PRIVATE KEY"
+	pemPublicKeyPKIX       = "PUBLIC KEY"
+	pemPublicKeyPKCS1      = "RSA PUBLIC KEY"
+)
+
+// ErrPassphraseRequired is returned when an encrypted private key is parsed without passphrase.
+var ErrPassphraseRequired = errors.New("private key is encrypted, passphrase is required")
+
+// Format is the encoding of a PEM key.
+type Format string
+
+const (
+	FormatPKCS1 Format = "pkcs1"
+	FormatPKCS8 Format = "pkcs8"
+	FormatPKIX  Format = "pkix"
+)
+
+// ParseFormat parses pkcs1, pkcs8 or pkix.
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(s); format {
+	case FormatPKCS1, FormatPKCS8, FormatPKIX:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown key format '%s', expected pkcs1, pkcs8 or pkix", s)
+	}
+}
+
+// Generate creates an RSA private key with a modulus of the given size.
+func Generate(bits int) (*rsa.PrivateKey, error) {
+	if bits < MinBits {
+		return nil, fmt.Errorf("key size %d is too small, at least %d bits are required", bits, MinBits)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate key")
+	}
+	return key, nil
+}
+
+// EncodePrivateKey encodes the private key as PKCS1 or PKCS8 PEM.
+// If the passphrase is not empty the key is encrypted with PBES2, which requires PKCS8.
+func EncodePrivateKey(key *rsa.PrivateKey, format Format, passphrase []byte) ([]byte, error) {
+	switch format {
+	case FormatPKCS1:
+		if len(passphrase) != 0 {
+			return nil, errors.New("encrypted private keys require the pkcs8 format")
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: pemPrivateKeyPKCS1, Bytes: x509.MarshalPKCS1PrivateKey(key)}), nil
+	case FormatPKCS8:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal private key")
+		}
+		if len(passphrase) == 0 {
+			return pem.EncodeToMemory(&pem.Block{Type: pemPrivateKeyPKCS8, Bytes: der}), nil
+		}
+		encrypted, err := EncryptPKCS8(der, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: pemEncryptedPrivateKey, Bytes: encrypted}), nil
+	default:
+		return nil, fmt.Errorf("unsupported private key format '%s', expected pkcs1 or pkcs8", format)
+	}
+}
+
+// EncodePublicKey encodes the public key as PKIX or PKCS1 PEM.
+func EncodePublicKey(key *rsa.PublicKey, format Format) ([]byte, error) {
+	switch format {
+	case FormatPKIX:
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal public key")
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: pemPublicKeyPKIX, Bytes: der}), nil
+	case FormatPKCS1:
+		return pem.EncodeToMemory(&pem.Block{Type: pemPublicKeyPKCS1, Bytes: x509.MarshalPKCS1PublicKey(key)}), nil
+	default:
+		return nil, fmt.Errorf("unsupported public key format '%s', expected pkix or pkcs1", format)
+	}
+}
+
+// ParsePrivateKey parses everything decryptor.ParsePrivateKey accepts and PEM encoded encrypted PKCS8 keys.
+func ParsePrivateKey(key []byte, passphrase []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(key)
+	if block == nil || block.Type != pemEncryptedPrivateKey {
+		return decryptor.ParsePrivateKey(key)
+	}
+	if len(passphrase) == 0 {
+		return nil, ErrPassphraseRequired
+	}
+	der, err := DecryptPKCS8(block.Bytes, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer decryptor.Zero(der)
+	return decryptor.ParsePrivateKey(der)
+}
+
+// SelfSignedCertificate creates the DER encoded certificate keystores require for a private key entry.
+func SelfSignedCertificate(key *rsa.PrivateKey, commonName string, validity time.Duration) ([]byte, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "generate serial number")
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageDataEncipherment,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "create certificate")
+	}
+	return der, nil
+}
@@ -0,0 +1,219 @@
+package keys
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"hash"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	oidData                = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509Certificate     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidFriendlyName        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidSHA256              = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+)
+
+type pfx struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue
+	Attributes []pkcs12Attribute `asn1:"set"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+// EncodePKCS12 encodes the private key and its certificate as PKCS#12 keystore entry with the alias.
+// The key is encrypted with PBES2 (AES-256-CBC) and the keystore integrity uses HMAC-SHA256,
+// the format of Java's PKCS12 keystore since JDK 12.
+func EncodePKCS12(key *rsa.PrivateKey, certificate []byte, alias string, password []byte) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("keystore password is required")
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal private key")
+	}
+	shroudedKey, err := EncryptPKCS8(der, password)
+	if err != nil {
+		return nil, err
+	}
+	localKeyID := sha1.Sum(certificate)
+	attributes, err := bagAttributes(alias, localKeyID[:])
+	if err != nil {
+		return nil, err
+	}
+	certValue, err := asn1.Marshal(certBag{ID: oidX509Certificate, Data: certificate})
+	if err != nil {
+		return nil, err
+	}
+	certContent, err := safeContents(safeBag{ID: oidCertBag, Value: explicit(certValue), Attributes: attributes})
+	if err != nil {
+		return nil, err
+	}
+	keyContent, err := safeContents(safeBag{ID: oidPKCS8ShroudedKeyBag, Value: explicit(shroudedKey), Attributes: attributes})
+	if err != nil {
+		return nil, err
+	}
+	authenticatedSafe, err := asn1.Marshal([]contentInfo{certContent, keyContent})
+	if err != nil {
+		return nil, err
+	}
+	authSafe, err := dataContent(authenticatedSafe)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "generate salt")
+	}
+	macKey := pkcs12KDF(sha256.New, 64, bmpString(string(password)), salt, pbkdf2Iterations, 3, sha256.Size)
+	mac := hmac.New(sha256.New, macKey)
+	_, _ = mac.Write(authenticatedSafe)
+	return asn1.Marshal(pfx{
+		Version:  3,
+		AuthSafe: authSafe,
+		MacData: macData{
+			Mac:        digestInfo{Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}, Digest: mac.Sum(nil)},
+			MacSalt:    salt,
+			Iterations: pbkdf2Iterations,
+		},
+	})
+}
+
+func bagAttributes(alias string, localKeyID []byte) ([]pkcs12Attribute, error) {
+	friendlyName, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagBMPString, Bytes: utf16BE(alias)})
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := asn1.Marshal(localKeyID)
+	if err != nil {
+		return nil, err
+	}
+	return []pkcs12Attribute{
+		{ID: oidFriendlyName, Value: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: friendlyName}},
+		{ID: oidLocalKeyID, Value: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: keyID}},
+	}, nil
+}
+
+func safeContents(bags ...safeBag) (contentInfo, error) {
+	content, err := asn1.Marshal(bags)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	return dataContent(content)
+}
+
+func dataContent(data []byte) (contentInfo, error) {
+	content, err := asn1.Marshal(data)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	return contentInfo{ContentType: oidData, Content: explicit(content)}, nil
+}
+
+// explicit wraps the DER encoding in the [0] EXPLICIT tag, encoding/asn1 ignores the tag of raw values.
+func explicit(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+// bmpString encodes the string as big-endian UTF-16 with a trailing NUL, the password encoding of RFC 7292.
+func bmpString(s string) []byte {
+	return append(utf16BE(s), 0, 0)
+}
+
+func utf16BE(s string) []byte {
+	encoded := utf16.Encode([]rune(s))
+	result := make([]byte, 0, 2*len(encoded))
+	for _, c := range encoded {
+		result = append(result, byte(c>>8), byte(c))
+	}
+	return result
+}
+
+// pkcs12KDF derives key material from the password as described in RFC 7292 appendix B.2,
+// v is the block size of the hash function and id the purpose of the material (3 for the MAC key).
+func pkcs12KDF(hashFunc func() hash.Hash, v int, password, salt []byte, iterations int, id byte, size int) []byte {
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		result := make([]byte, v*((len(b)+v-1)/v))
+		for i := range result {
+			result[i] = b[i%len(b)]
+		}
+		return result
+	}
+	d := make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+	i := append(fill(salt), fill(password)...)
+
+	var result []byte
+	for len(result) < size {
+		h := hashFunc()
+		_, _ = h.Write(d)
+		_, _ = h.Write(i)
+		a := h.Sum(nil)
+		for r := 1; r < iterations; r++ {
+			h.Reset()
+			_, _ = h.Write(a)
+			a = h.Sum(a[:0])
+		}
+		result = append(result, a...)
+
+		b := fill(a)[:v]
+		for j := 0; j < len(i); j += v {
+			// I_j = (I_j + B + 1) mod 2^(8v)
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(i[j+k]) + int(b[k]) + carry
+				i[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+	return result[:size]
+}
@@ -0,0 +1,169 @@
+// Package kms decrypts the {cipher} values of the spring-cloud-config-aws-kms TextEncryptor with the AWS KMS Decrypt API.
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	serviceName    = "kms"
+	decryptTarget  = "TrentService.Decrypt"
+	contentType    = "application/x-amz-json-1.1"
+)
+
+// DecryptInput is the request of the KMS Decrypt API.
+type DecryptInput struct {
+	CiphertextBlob      []byte            `json:"CiphertextBlob"`
+	EncryptionContext   map[string]string `json:"EncryptionContext,omitempty"`
+	KeyId               string            `json:"KeyId,omitempty"`
+	EncryptionAlgorithm string            `json:"EncryptionAlgorithm,omitempty"`
+}
+
+// DecryptOutput is the response of the KMS Decrypt API.
+type DecryptOutput struct {
+	KeyId               string `json:"KeyId"`
+	Plaintext           []byte `json:"Plaintext"`
+	EncryptionAlgorithm string `json:"EncryptionAlgorithm,omitempty"`
+}
+
+// Error is an error returned by the KMS API e.g. InvalidCiphertextException.
+type Error struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("kms %s (status %d)", e.Type, e.StatusCode)
+	}
+	return fmt.Sprintf("kms %s (status %d): %s", e.Type, e.StatusCode, e.Message)
+}
+
+type ClientOption func(client *Client) error
+
+// Client calls the KMS JSON API signed with AWS Signature Version 4.
+type Client struct {
+	endpoint    *url.URL
+	region      string
+	credentials Credentials
+	httpClient  *http.Client
+	now         func() time.Time
+}
+
+// NewClient creates a client for the region, the endpoint defaults to https://kms.<region>.amazonaws.com.
+func NewClient(region string, credentials Credentials, options ...ClientOption) (*Client, error) {
+	if region == "" {
+		return nil, errors.New("kms region is required")
+	}
+	endpoint, _ := url.Parse(fmt.Sprintf("https://kms.%s.amazonaws.com", region))
+	result := &Client{
+		endpoint:    endpoint,
+		region:      region,
+		credentials: credentials,
+		httpClient:  &http.Client{Timeout: defaultTimeout},
+		now:         time.Now,
+	}
+	for _, option := range options {
+		if err := option(result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// WithEndpoint overrides the KMS endpoint, e.g. for a local KMS stand-in or a VPC endpoint.
+func WithEndpoint(endpoint string) ClientOption {
+	return func(client *Client) error {
+		parsed, err := url.Parse(endpoint)
+		if err != nil {
+			return errors.Wrapf(err, "kms endpoint '%s' parse error", endpoint)
+		}
+		if parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return fmt.Errorf("kms endpoint '%s' must be http or https", endpoint)
+		}
+		client.endpoint = parsed
+		return nil
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(client *Client) error {
+		client.httpClient = httpClient
+		return nil
+	}
+}
+
+// Decrypt calls the KMS Decrypt API.
+func (c *Client) Decrypt(ctx context.Context, input *DecryptInput) (*DecryptOutput, error) {
+	output := &DecryptOutput{}
+	if err := c.call(ctx, decryptTarget, input, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *Client) call(ctx context.Context, target string, input interface{}, output interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return errors.Wrap(err, "marshal kms request")
+	}
+	u := *c.endpoint
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "create kms request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Amz-Target", target)
+	signV4(req, body, c.credentials, c.region, serviceName, c.now())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "kms %s request", target)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "read kms response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return newError(resp.StatusCode, data)
+	}
+	return errors.Wrap(json.Unmarshal(data, output), "unmarshal kms response")
+}
+
+func newError(statusCode int, data []byte) error {
+	var body struct {
+		Type         string `json:"__type"`
+		Message      string `json:"message"`
+		MessageUpper string `json:"Message"`
+	}
+	_ = json.Unmarshal(data, &body)
+	result := &Error{StatusCode: statusCode, Type: body.Type, Message: body.Message}
+	if result.Message == "" {
+		result.Message = body.MessageUpper
+	}
+	// the type may be qualified e.g. com.amazonaws.kms#InvalidCiphertextException
+	if i := strings.LastIndex(result.Type, "#"); i != -1 {
+		result.Type = result.Type[i+1:]
+	}
+	if result.Type == "" {
+		result.Type = http.StatusText(statusCode)
+	}
+	return result
+}
@@ -0,0 +1,106 @@
+package kms
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+)
+
+// Encryption algorithms of the {algorithm:} prefix, SYMMETRIC_DEFAULT is used without prefix.
+const (
+	AlgorithmSymmetricDefault = "SYMMETRIC_DEFAULT"
+	AlgorithmRSAOAEPSHA1      = "RSAES_OAEP_SHA_1"
+	AlgorithmRSAOAEPSHA256    = "RSAES_OAEP_SHA_256"
+)
+
+type ValueDecryptorOption func(decryptor *ValueDecryptor) error
+
+// ValueDecryptor decrypts {cipher} values written by the spring-cloud-config-aws-kms TextEncryptor:
+// a base64 KMS ciphertext blob with the optional {key:id}, {context:k1=v1,k2=v2} and {algorithm:name} prefixes.
+type ValueDecryptor struct {
+	client *Client
+	keyID  string
+}
+
+func NewValueDecryptor(client *Client, options ...ValueDecryptorOption) (*ValueDecryptor, error) {
+	result := &ValueDecryptor{client: client}
+	for _, option := range options {
+		if err := option(result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// WithKeyID sets the KMS key id, ARN or alias used for values without {key:} prefix.
+// Symmetric ciphertext blobs contain the key, asymmetric ones require it.
+func WithKeyID(keyID string) ValueDecryptorOption {
+	return func(decryptor *ValueDecryptor) error {
+		decryptor.keyID = keyID
+		return nil
+	}
+}
+
+func (d ValueDecryptor) DecryptValue(value string) (string, error) {
+	if !strings.HasPrefix(value, decryptor.CipherPrefix) {
+		return value, nil
+	}
+	plainText, _, err := d.DecryptValueWithKey(value)
+	if err != nil {
+		return "", err
+	}
+	defer decryptor.Zero(plainText)
+	return string(plainText), nil
+}
+
+// DecryptValueWithKey decrypts the {cipher} value and returns the ARN of the KMS key which decrypted it,
+// empty if the value is not encrypted.
+func (d ValueDecryptor) DecryptValueWithKey(value string) ([]byte, string, error) {
+	if !strings.HasPrefix(value, decryptor.CipherPrefix) {
+		return []byte(value), "", nil
+	}
+	value, prefixes := decryptor.ParseCipherValue(value)
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		// do not include the value, the error messages may end up in logs
+		return nil, "", fmt.Errorf("%w: %v", decryptor.ErrInvalidBase64, err)
+	}
+	input := &DecryptInput{
+		CiphertextBlob:      data,
+		KeyId:               d.keyID,
+		EncryptionAlgorithm: prefixes["algorithm"],
+	}
+	if keyID, ok := prefixes["key"]; ok {
+		input.KeyId = keyID
+	}
+	if encryptionContext, ok := prefixes["context"]; ok {
+		input.EncryptionContext, err = parseContext(encryptionContext)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	output, err := d.client.Decrypt(context.Background(), input)
+	if err != nil {
+		return nil, "", err
+	}
+	return output.Plaintext, output.KeyId, nil
+}
+
+// parseContext parses the k1=v1,k2=v2 encryption context of the {context:} prefix.
+func parseContext(s string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid encryption context entry '%s', expected key=value", pair)
+		}
+		result[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return result, nil
+}
@@ -0,0 +1,110 @@
+package kms
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignV4(t *testing.T) {
+	// get-vanilla of the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials := Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signV4(req, nil, credentials, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if actual := req.Header.Get("Authorization"); actual != expected {
+		t.Errorf("Authorization differs:\nexpected %s\nactual   %s", expected, actual)
+	}
+}
+
+// kmsStandIn answers the Decrypt API for a single ciphertext blob.
+func kmsStandIn(t *testing.T, blob []byte, encryptionContext map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		if r.Header.Get("X-Amz-Target") != decryptTarget ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/eu-central-1/kms/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-target, Signature=") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"com.amazon.coral.service#InvalidSignatureException","message":"bad signature"}`))
+			return
+		}
+		var input DecryptInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if !bytes.Equal(input.CiphertextBlob, blob) || !reflect.DeepEqual(input.EncryptionContext, encryptionContext) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"InvalidCiphertextException"}`))
+			return
+		}
+		keyID := input.KeyId
+		if keyID == "" {
+			keyID = "arn:aws:kms:eu-central-1:123456789012:key/default"
+		}
+		_ = json.NewEncoder(w).Encode(&DecryptOutput{KeyId: keyID, Plaintext: []byte("secret"), EncryptionAlgorithm: AlgorithmSymmetricDefault})
+	}))
+}
+
+func TestValueDecryptor(t *testing.T) {
+	blob := append([]byte{0x01, 0x02}, bytes.Repeat([]byte{0xab}, 200)...)
+	server := kmsStandIn(t, blob, map[string]string{"app": "orders", "env": "prod"})
+	defer server.Close()
+
+	client, err := NewClient("eu-central-1", Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valueDecryptor, err := NewValueDecryptor(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(blob)
+
+	tests := []struct {
+		name  string
+		value string
+		key   string
+		err   string
+	}{
+		{name: "context", value: "{cipher}{context:app=orders, env=prod}" + encoded, key: "arn:aws:kms:eu-central-1:123456789012:key/default"},
+		{name: "key", value: "{cipher}{key:alias/config}{context:env=prod,app=orders}" + encoded, key: "alias/config"},
+		{name: "wrong context", value: "{cipher}{context:env=dev}" + encoded, err: "kms InvalidCiphertextException (status 400)"},
+		{name: "invalid context", value: "{cipher}{context:env}" + encoded, err: "invalid encryption context entry 'env', expected key=value"},
+		{name: "base64", value: "{cipher}{context:env=prod}%%%", err: "value cannot be base64 decoded: illegal base64 data at input byte 0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			plainText, key, err := valueDecryptor.DecryptValueWithKey(tc.value)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected error %q, actual %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decrypt error: %v", err)
+			}
+			if string(plainText) != "secret" || key != tc.key {
+				t.Errorf("Unexpected result %q %q", plainText, key)
+			}
+		})
+	}
+
+	client, _ = NewClient("eu-central-1", Credentials{AccessKeyID: "OTHER", SecretAccessKey: "secret"}, WithEndpoint(server.URL))
+	_, _, err = (&ValueDecryptor{client: client}).DecryptValueWithKey("{cipher}" + encoded)
+	var kmsErr *Error
+	if !errors.As(err, &kmsErr) || kmsErr.Type != "InvalidSignatureException" || kmsErr.Message != "bad signature" {
+		t.Errorf("Expected signature error, actual %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package kms
+
+import (
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+)
+
+func init() {
+	decryptor.RegisterScheme("kms", newScheme)
+}
+
+// newScheme creates the scheme of the {cipher} values which are not RSA envelopes,
+// the credentials are read from the environment.
+func newScheme(config decryptor.SchemeConfig) (decryptor.Scheme, error) {
+	if err := config.Validate("region", "endpoint", "key_id"); err != nil {
+		return nil, err
+	}
+	credentials, err := CredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	var options []ClientOption
+	if endpoint := config["endpoint"]; endpoint != "" {
+		options = append(options, WithEndpoint(endpoint))
+	}
+	client, err := NewClient(config.Get("region", RegionFromEnv()), credentials, options...)
+	if err != nil {
+		return nil, err
+	}
+	valueDecryptor, err := NewValueDecryptor(client, WithKeyID(config["key_id"]))
+	if err != nil {
+		return nil, err
+	}
+	return decryptor.NewNonEnvelopeScheme(valueDecryptor), nil
+}
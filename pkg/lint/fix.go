@@ -0,0 +1,206 @@
+package lint
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+)
+
+var (
+	fixPrefixPattern   = regexp.MustCompile(`^{\s*([A-Za-z]+)\s*:\s*([^{}]*?)\s*}`)
+	base64LinePattern  = regexp.MustCompile(`^[A-Za-z0-9+/=]+$`)
+	whitespacePattern  = regexp.MustCompile(`\s+`)
+	lineEndingsPattern = regexp.MustCompile(`\r?\n$`)
+)
+
+// Change is a single rewrite made by Fix.
+type Change struct {
+	// Line is the 1-based number of the first replaced line
+	Line int
+	Old  []string
+	New  []string
+}
+
+// Fix quotes and normalizes the {cipher} values, lines without {cipher} values are left byte-for-byte unchanged:
+//   - unquoted YAML values are single-quoted
+//   - base64 wrapped across lines is joined
+//   - whitespace inside the cipher text is removed
+//   - {key:...} like prefixes are normalized
+func Fix(fileName string, content []byte) ([]byte, []Change) {
+	var (
+		sb      strings.Builder
+		changes []Change
+	)
+	yaml := strings.ToLower(filepath.Ext(fileName)) != ".properties"
+	lines := splitLines(string(content))
+	paths := properties.NewPathTracker()
+	for i := 0; i < len(lines); i++ {
+		line := paths.Next(lines[i])
+		if !line.HasValue || !strings.HasPrefix(strings.TrimSpace(lineValue(lines[i], line)), cipherPrefix) {
+			sb.WriteString(lines[i])
+			continue
+		}
+		var (
+			replacement string
+			consumed    int
+		)
+		if yaml {
+			replacement, consumed = fixYAMLValue(lines[i:], line)
+		} else {
+			replacement, consumed = fixPropertiesValue(lines[i:], line)
+		}
+		old := lines[i : i+consumed]
+		if replacement != strings.Join(old, "") {
+			changes = append(changes, Change{Line: i + 1, Old: trimLineEndings(old), New: trimLineEndings([]string{replacement})})
+		}
+		sb.WriteString(replacement)
+		// the skipped continuation lines do not change the property paths
+		i += consumed - 1
+	}
+	return []byte(sb.String()), changes
+}
+
+// lineValue returns the raw value text of the line starting at the value column.
+func lineValue(raw string, line properties.Line) string {
+	text := lineEndingsPattern.ReplaceAllString(raw, "")
+	if line.Column-1 > len(text) {
+		return ""
+	}
+	value := text[line.Column-1:]
+	if line.Quote != 0 {
+		value = value[1:]
+	}
+	return value
+}
+
+func fixYAMLValue(lines []string, line properties.Line) (string, int) {
+	first := lineEndingsPattern.ReplaceAllString(lines[0], "")
+	head := first[:line.Column-1]
+	indent := len(first) - len(strings.TrimLeft(first, " \t"))
+
+	var (
+		cipherText strings.Builder
+		tail       string
+		consumed   = 1
+	)
+	if line.Quote == 0 {
+		cipherText.WriteString(line.Value)
+		tail = first[line.Column-1+len(line.Value):]
+		// plain multi-line scalars continue on more indented lines
+		for consumed < len(lines) {
+			next := strings.TrimSpace(lines[consumed])
+			nextIndent := len(lines[consumed]) - len(strings.TrimLeft(lines[consumed], " \t"))
+			if next == "" || nextIndent <= indent || !base64LinePattern.MatchString(next) {
+				break
+			}
+			cipherText.WriteString(next)
+			consumed++
+		}
+	} else {
+		rest := first[line.Column:]
+		for {
+			if end := strings.IndexByte(rest, line.Quote); end != -1 {
+				cipherText.WriteString(rest[:end])
+				tail = rest[end+1:]
+				break
+			}
+			cipherText.WriteString(rest)
+			if consumed == len(lines) {
+				// unterminated quoted value, leave it as it is
+				return strings.Join(lines[:1], ""), 1
+			}
+			rest = lineEndingsPattern.ReplaceAllString(lines[consumed], "")
+			consumed++
+		}
+	}
+	quote := line.Quote
+	if quote == 0 {
+		quote = '\''
+	}
+	eol := lineEndingsPattern.FindString(lines[consumed-1])
+	return head + string(quote) + normalizeCipher(cipherText.String()) + string(quote) + tail + eol, consumed
+}
+
+func fixPropertiesValue(lines []string, line properties.Line) (string, int) {
+	first := lineEndingsPattern.ReplaceAllString(lines[0], "")
+	head := first[:line.Column-1]
+
+	var cipherText strings.Builder
+	value := line.Value
+	consumed := 1
+	// backslash at the end of line continues the value on the next line
+	for strings.HasSuffix(value, `\`) && consumed < len(lines) {
+		cipherText.WriteString(strings.TrimSuffix(value, `\`))
+		value = strings.TrimSpace(lines[consumed])
+		consumed++
+	}
+	cipherText.WriteString(value)
+	eol := lineEndingsPattern.FindString(lines[consumed-1])
+	return head + normalizeCipher(cipherText.String()) + eol, consumed
+}
+
+// normalizeCipher removes the whitespace and normalizes the prefixes of the {cipher} value.
+func normalizeCipher(value string) string {
+	var sb strings.Builder
+	sb.WriteString(cipherPrefix)
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), cipherPrefix))
+	for {
+		m := fixPrefixPattern.FindStringSubmatch(value)
+		if m == nil {
+			break
+		}
+		_, _ = fmt.Fprintf(&sb, "{%s:%s}", strings.ToLower(m[1]), m[2])
+		value = strings.TrimSpace(value[len(m[0]):])
+	}
+	sb.WriteString(whitespacePattern.ReplaceAllString(value, ""))
+	return sb.String()
+}
+
+func splitLines(content string) []string {
+	var lines []string
+	for content != "" {
+		i := strings.IndexByte(content, '\n')
+		if i == -1 {
+			lines = append(lines, content)
+			break
+		}
+		lines = append(lines, content[:i+1])
+		content = content[i+1:]
+	}
+	return lines
+}
+
+func trimLineEndings(lines []string) []string {
+	var result []string
+	for _, line := range lines {
+		result = append(result, strings.Split(lineEndingsPattern.ReplaceAllString(line, ""), "\n")...)
+	}
+	return result
+}
+
+// WriteDiff writes the changes as a unified diff without context lines.
+func WriteDiff(w io.Writer, fileName string, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	wr := bufio.NewWriter(w)
+	name := strings.TrimPrefix(filepath.ToSlash(fileName), "/")
+	_, _ = fmt.Fprintf(wr, "--- a/%s\n+++ b/%s\n", name, name)
+	offset := 0
+	for _, change := range changes {
+		_, _ = fmt.Fprintf(wr, "@@ -%d,%d +%d,%d @@\n", change.Line, len(change.Old), change.Line+offset, len(change.New))
+		for _, line := range change.Old {
+			_, _ = fmt.Fprintf(wr, "-%s\n", line)
+		}
+		for _, line := range change.New {
+			_, _ = fmt.Fprintf(wr, "+%s\n", line)
+		}
+		offset += len(change.New) - len(change.Old)
+	}
+	return wr.Flush()
+}
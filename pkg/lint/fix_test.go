@@ -0,0 +1,77 @@
+package lint
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFix(t *testing.T) {
+	tt := []struct {
+		name     string
+		fileName string
+		content  string
+
+		expected string
+		changes  int
+	}{
+		{name: "Quote plain value",
+			fileName: "app.yml",
+			content:  "db:\n  # comment: {cipher}\n  password: {cipher}AQA= # secret\n  user: app\n",
+			expected: "db:\n  # comment: {cipher}\n  password: '{cipher}AQA=' # secret\n  user: app\n",
+			changes:  1},
+		{name: "Already quoted values are unchanged",
+			fileName: "app.yml",
+			content:  "a: '{cipher}AQA='\r\nb: \"{cipher}{key:k}AQB=\"\r\n",
+			expected: "a: '{cipher}AQA='\r\nb: \"{cipher}{key:k}AQB=\"\r\n"},
+		{name: "Join wrapped plain value",
+			fileName: "app.yml",
+			content:  "db:\n  password: {cipher}AQA\n    BBB\n    CC==\n  user: app",
+			expected: "db:\n  password: '{cipher}AQABBBCC=='\n  user: app",
+			changes:  1},
+		{name: "Join wrapped quoted value and normalize prefix",
+			fileName: "app.yml",
+			content:  "list:\n  - \"{cipher}{ KEY : old }AQA\n    BB==\"\n  - other\n",
+			expected: "list:\n  - \"{cipher}{key:old}AQABB==\"\n  - other\n",
+			changes:  1},
+		{name: "Remove stray whitespace",
+			fileName: "app.yml",
+			content:  "password: '{cipher}AQ A\tB=='\n",
+			expected: "password: '{cipher}AQAB=='\n",
+			changes:  1},
+		{name: "Properties continuation",
+			fileName: "app.properties",
+			content:  "db.password={cipher}AQA\\\n    BB==\ndb.user=app\n",
+			expected: "db.password={cipher}AQABB==\ndb.user=app\n",
+			changes:  1},
+	}
+	for _, tc := range tt {
+		actual, changes := Fix(tc.fileName, []byte(tc.content))
+		if string(actual) != tc.expected {
+			t.Errorf("%s: values differ: expected %q, actual %q", tc.name, tc.expected, actual)
+		}
+		if len(changes) != tc.changes {
+			t.Errorf("%s: changes differ: expected %d, actual %v", tc.name, tc.changes, changes)
+		}
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	_, changes := Fix("app.yml", []byte("a: {cipher}AQ\n  BB\nb: {cipher}CC\n"))
+	buf := new(bytes.Buffer)
+	if err := WriteDiff(buf, "app.yml", changes); err != nil {
+		t.Fatalf("write diff error: %v", err)
+	}
+	expected := `--- a/app.yml
++++ b/app.yml
+@@ -1,2 +1,1 @@
+-a: {cipher}AQ
+-  BB
++a: '{cipher}AQBB'
+@@ -3,1 +2,1 @@
+-b: {cipher}CC
++b: '{cipher}CC'
+`
+	if actual := buf.String(); actual != expected {
+		t.Errorf("Values differ: expected %v, actual %v", expected, actual)
+	}
+}
@@ -0,0 +1,284 @@
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+	"github.com/pkg/errors"
+)
+
+const (
+	cipherPrefix = "{cipher}"
+	// IgnoreDirective in a trailing comment suppresses the findings of the line,
+	// in a whole line comment it suppresses the findings of the next line.
+	IgnoreDirective = "lint:ignore"
+
+	defaultEntropyThreshold = 3.5
+	defaultMinSecretLength  = 20
+)
+
+// DefaultSensitivePatterns are the property name patterns which are expected to be encrypted.
+var DefaultSensitivePatterns = []string{"password", "secret", "token", "*.credentials.*"}
+
+// Rule identifies the kind of a finding.
+type Rule string
+
+const (
+	RuleSensitiveProperty Rule = "sensitive-property-not-encrypted"
+	RuleHighEntropyValue  Rule = "high-entropy-value-not-encrypted"
+	RuleUnquotedCipher    Rule = "unquoted-cipher-value"
+)
+
+// Rules describes all rules.
+var Rules = map[Rule]string{
+	RuleSensitiveProperty: "The property name looks sensitive but the value is not encrypted",
+	RuleHighEntropyValue:  "The value looks like a secret but is not encrypted",
+	RuleUnquotedCipher:    "The {cipher} value is not quoted, YAML parses it as a flow mapping",
+}
+
+// Finding is a single policy violation.
+type Finding struct {
+	Rule    Rule
+	File    string
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", f.File, f.Line, f.Column, f.Message)
+}
+
+type allowEntry struct {
+	file     string
+	property string
+}
+
+type Option func(linter *Linter) error
+
+// Linter detects sensitive properties which are not encrypted.
+type Linter struct {
+	patterns         []string
+	entropyThreshold float64
+	minSecretLength  int
+	allowlist        []allowEntry
+}
+
+func New(options ...Option) (*Linter, error) {
+	result := &Linter{
+		entropyThreshold: defaultEntropyThreshold,
+		minSecretLength:  defaultMinSecretLength,
+	}
+	if err := WithSensitivePatterns(DefaultSensitivePatterns)(result); err != nil {
+		return nil, err
+	}
+	for _, option := range options {
+		if err := option(result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// WithSensitivePatterns sets the property name patterns. A pattern without wildcards matches
+// when the last segment of the property name contains it, otherwise the whole name must match the glob.
+func WithSensitivePatterns(patterns []string) Option {
+	return func(linter *Linter) error {
+		linter.patterns = nil
+		for _, pattern := range patterns {
+			pattern = normalize(strings.TrimSpace(pattern))
+			if pattern == "" {
+				continue
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("sensitive pattern '%s' is invalid: %v", pattern, err)
+			}
+			linter.patterns = append(linter.patterns, pattern)
+		}
+		return nil
+	}
+}
+
+// WithEntropyThreshold sets the Shannon entropy in bits per character above which a value looks like a secret.
+// Zero disables the check.
+func WithEntropyThreshold(threshold float64) Option {
+	return func(linter *Linter) error {
+		if threshold < 0 {
+			return fmt.Errorf("entropy threshold %v must not be negative", threshold)
+		}
+		linter.entropyThreshold = threshold
+		return nil
+	}
+}
+
+// WithAllowlist reads the allowlist entries, one per line, either "property-glob" or "file-glob:property-glob".
+func WithAllowlist(r io.Reader) Option {
+	return func(linter *Linter) error {
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			entry := allowEntry{file: "*", property: line}
+			if i := strings.LastIndexByte(line, ':'); i != -1 {
+				entry = allowEntry{file: strings.TrimSpace(line[:i]), property: strings.TrimSpace(line[i+1:])}
+			}
+			entry.property = normalize(entry.property)
+			if _, err := path.Match(entry.property, ""); err != nil {
+				return fmt.Errorf("allowlist entry '%s' is invalid: %v", line, err)
+			}
+			if _, err := path.Match(entry.file, ""); err != nil {
+				return fmt.Errorf("allowlist entry '%s' is invalid: %v", line, err)
+			}
+			linter.allowlist = append(linter.allowlist, entry)
+		}
+		return errors.Wrap(sc.Err(), "allowlist reading error")
+	}
+}
+
+// Supported reports whether the file is a YAML or properties file.
+func Supported(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yml", ".yaml", ".properties":
+		return true
+	}
+	return false
+}
+
+// Lint checks the content of a YAML or properties file.
+func (l *Linter) Lint(fileName string, content []byte) []Finding {
+	var (
+		findings   []Finding
+		ignoreNext bool
+	)
+	yaml := strings.ToLower(filepath.Ext(fileName)) != ".properties"
+	paths := properties.NewPathTracker()
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Buffer(make([]byte, 64*1024), len(content)+1)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := paths.Next(sc.Text())
+		ignored := ignoreNext || strings.Contains(line.Comment, IgnoreDirective)
+		ignoreNext = !line.HasValue && strings.Contains(line.Comment, IgnoreDirective)
+		if !line.HasValue || ignored || l.allowed(fileName, line.Path) {
+			continue
+		}
+		newFinding := func(rule Rule, message string) Finding {
+			return Finding{Rule: rule, File: fileName, Line: lineNo, Column: line.Column, Path: line.Path, Message: message}
+		}
+		if strings.HasPrefix(line.Value, cipherPrefix) {
+			if yaml && line.Quote == 0 {
+				findings = append(findings, newFinding(RuleUnquotedCipher,
+					fmt.Sprintf("property '%s' has an unquoted {cipher} value, use single quotes", line.Path)))
+			}
+			continue
+		}
+		if !looksLikeSecret(line.Value) {
+			continue
+		}
+		if l.sensitive(line.Path) {
+			findings = append(findings, newFinding(RuleSensitiveProperty,
+				fmt.Sprintf("property '%s' looks sensitive but is not encrypted", line.Path)))
+		} else if l.highEntropy(line.Value) {
+			findings = append(findings, newFinding(RuleHighEntropyValue,
+				fmt.Sprintf("property '%s' has a high entropy value which is not encrypted", line.Path)))
+		}
+	}
+	return findings
+}
+
+func (l *Linter) sensitive(propertyPath string) bool {
+	name := normalize(propertyPath)
+	last := name
+	if i := strings.LastIndexByte(name, '.'); i != -1 {
+		last = name[i+1:]
+	}
+	for _, pattern := range l.patterns {
+		if strings.ContainsAny(pattern, "*?[") {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		} else if strings.Contains(last, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Linter) highEntropy(value string) bool {
+	if l.entropyThreshold == 0 || len(value) < l.minSecretLength {
+		return false
+	}
+	var letters, digits bool
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			letters = true
+		case r >= '0' && r <= '9':
+			digits = true
+		case strings.ContainsRune("+/=_-", r):
+		default:
+			return false
+		}
+	}
+	return letters && digits && entropy(value) >= l.entropyThreshold
+}
+
+func (l *Linter) allowed(fileName, propertyPath string) bool {
+	name := normalize(propertyPath)
+	file := filepath.ToSlash(fileName)
+	for _, entry := range l.allowlist {
+		fileMatch, _ := path.Match(entry.file, file)
+		if !fileMatch {
+			fileMatch, _ = path.Match(entry.file, path.Base(file))
+		}
+		if propertyMatch, _ := path.Match(entry.property, name); fileMatch && propertyMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeSecret filters out values which cannot be secrets like empty values, numbers, booleans and placeholders.
+func looksLikeSecret(value string) bool {
+	switch strings.ToLower(value) {
+	case "", "~", "null", "true", "false", "yes", "no", "on", "off", "|", "|-", ">", ">-", "<n/a>":
+		return false
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return false
+	}
+	if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") {
+		return false
+	}
+	return true
+}
+
+// entropy returns the Shannon entropy of the value in bits per character.
+func entropy(value string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range value {
+		counts[r]++
+	}
+	var result float64
+	n := float64(len([]rune(value)))
+	for _, count := range counts {
+		p := float64(count) / n
+		result -= p * math.Log2(p)
+	}
+	return result
+}
+
+// normalize converts a property name to its canonical form for matching, as Spring relaxed binding does.
+func normalize(name string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(name))
+}
@@ -0,0 +1,159 @@
+// Package placeholders resolves Spring's ${name:default} placeholders in property values.
+package placeholders
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+)
+
+const (
+	prefix    = "${"
+	suffix    = "}"
+	separator = ":"
+)
+
+type ResolverOption func(*Resolver)
+
+// Resolver replaces the placeholders of property values with other properties or environment variables
+// the way Spring's PropertySourcesPlaceholderConfigurer does.
+type Resolver struct {
+	strict    bool
+	lookupEnv func(name string) (string, bool)
+}
+
+// NewResolver creates a resolver looking up the environment variables of the process.
+func NewResolver(options ...ResolverOption) *Resolver {
+	resolver := &Resolver{lookupEnv: os.LookupEnv}
+	for _, option := range options {
+		option(resolver)
+	}
+	return resolver
+}
+
+// WithStrict fails on placeholders which cannot be resolved and have no default, otherwise they are kept as they are.
+func WithStrict(strict bool) ResolverOption {
+	return func(resolver *Resolver) {
+		resolver.strict = strict
+	}
+}
+
+// WithEnv sets the lookup of the environment variables, nil disables them.
+func WithEnv(lookupEnv func(name string) (string, bool)) ResolverOption {
+	return func(resolver *Resolver) {
+		resolver.lookupEnv = lookupEnv
+	}
+}
+
+// Resolve returns the properties with the placeholders of the string values resolved.
+func (r *Resolver) Resolve(props properties.Properties) (properties.Properties, error) {
+	result := make(properties.Properties, len(props))
+	for _, key := range props.Keys() {
+		value, ok := props[key].(string)
+		if !ok {
+			result[key] = props[key]
+			continue
+		}
+		resolved, err := r.resolve(props, value, map[string]bool{})
+		if err != nil {
+			return nil, fmt.Errorf("property '%s': %v", key, err)
+		}
+		result[key] = resolved
+	}
+	return result, nil
+}
+
+// ResolveValue resolves the placeholders of a single value against the properties.
+func (r *Resolver) ResolveValue(props properties.Properties, value string) (string, error) {
+	return r.resolve(props, value, map[string]bool{})
+}
+
+// resolve replaces the placeholders of the value, visiting are the placeholders being resolved to detect cycles.
+func (r *Resolver) resolve(props properties.Properties, value string, visiting map[string]bool) (string, error) {
+	var sb strings.Builder
+	for {
+		start := strings.Index(value, prefix)
+		if start == -1 {
+			break
+		}
+		end := findEnd(value, start+len(prefix))
+		if end == -1 {
+			break
+		}
+		sb.WriteString(value[:start])
+		replacement, err := r.resolvePlaceholder(props, value[start+len(prefix):end], visiting)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(replacement)
+		value = value[end+len(suffix):]
+	}
+	sb.WriteString(value)
+	return sb.String(), nil
+}
+
+// resolvePlaceholder resolves the text between ${ and }, nested placeholders in the name are resolved first.
+func (r *Resolver) resolvePlaceholder(props properties.Properties, placeholder string, visiting map[string]bool) (string, error) {
+	if visiting[placeholder] {
+		return "", fmt.Errorf("circular placeholder reference '%s'", placeholder)
+	}
+	visiting[placeholder] = true
+	defer delete(visiting, placeholder)
+
+	name, err := r.resolve(props, placeholder, visiting)
+	if err != nil {
+		return "", err
+	}
+	value, ok := r.lookup(props, name)
+	if !ok {
+		if i := strings.Index(name, separator); i != -1 {
+			if value, ok = r.lookup(props, name[:i]); !ok {
+				value, ok = name[i+len(separator):], true
+			}
+		}
+	}
+	if !ok {
+		if r.strict {
+			return "", fmt.Errorf("could not resolve placeholder '%s'", name)
+		}
+		return prefix + placeholder + suffix, nil
+	}
+	return r.resolve(props, value, visiting)
+}
+
+// lookup returns the environment variable of the name or of its relaxed form like SPRING_DATASOURCE_URL,
+// otherwise the property. The environment wins as in Spring.
+func (r *Resolver) lookup(props properties.Properties, name string) (string, bool) {
+	if r.lookupEnv != nil {
+		if value, ok := r.lookupEnv(name); ok {
+			return value, true
+		}
+		if value, ok := r.lookupEnv(properties.EnvName(name)); ok {
+			return value, true
+		}
+	}
+	if value, ok := props[name]; ok {
+		return properties.ToString(value), true
+	}
+	return "", false
+}
+
+// findEnd returns the index of the } closing the placeholder starting at start, skipping nested placeholders.
+func findEnd(value string, start int) int {
+	depth := 0
+	for i := start; i < len(value); i++ {
+		switch {
+		case strings.HasPrefix(value[i:], prefix):
+			depth++
+			i += len(prefix) - 1
+		case strings.HasPrefix(value[i:], suffix):
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
@@ -0,0 +1,77 @@
+package placeholders
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+)
+
+func TestResolve(t *testing.T) {
+	env := map[string]string{"DB_PASSWORD": "env-secret", "SPRING_DATASOURCE_USERNAME": "env-user", "HOST_KEY": "db"}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	tests := []struct {
+		name     string
+		props    properties.Properties
+		strict   bool
+		expected properties.Properties
+		err      string
+	}{
+		{
+			name:     "properties",
+			props:    properties.Properties{"db.host": "localhost", "db.port": 5432, "url": "jdbc:postgresql://${db.host}:${db.port}/app"},
+			expected: properties.Properties{"db.host": "localhost", "db.port": 5432, "url": "jdbc:postgresql://localhost:5432/app"},
+		},
+		{
+			name:     "env and defaults",
+			props:    properties.Properties{"a": "${DB_PASSWORD:default}", "b": "${MISSING:default}", "c": "${spring.datasource.username}", "d": "${MISSING:}"},
+			expected: properties.Properties{"a": "env-secret", "b": "default", "c": "env-user", "d": ""},
+		},
+		{
+			name:     "nested",
+			props:    properties.Properties{"db.host": "h", "a": "${${HOST_KEY}.host}", "b": "${missing:${db.host}}", "c": "${b}", "password": "decrypted"},
+			expected: properties.Properties{"db.host": "h", "a": "h", "b": "h", "c": "h", "password": "decrypted"},
+		},
+		{
+			name:     "unresolved",
+			props:    properties.Properties{"a": "${missing} ${x", "b": "${a}"},
+			expected: properties.Properties{"a": "${missing} ${x", "b": "${missing} ${x"},
+		},
+		{
+			name:   "strict",
+			props:  properties.Properties{"a": "x", "b": "${a} ${missing}"},
+			strict: true,
+			err:    "property 'b': could not resolve placeholder 'missing'",
+		},
+		{
+			name:  "cycle",
+			props: properties.Properties{"a": "${b}", "b": "${c:${a}}"},
+			err:   "property 'a': circular placeholder reference 'b'",
+		},
+		{
+			name:     "repeated",
+			props:    properties.Properties{"a": "x", "b": "${a}${a}"},
+			expected: properties.Properties{"a": "x", "b": "xx"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := NewResolver(WithStrict(tc.strict), WithEnv(lookupEnv)).Resolve(tc.props)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected error %q, actual %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Properties differ: expected %v, actual %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package properties
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	yamlKeyPattern       = regexp.MustCompile(`^(\s*)(?:("[^"]*"|'[^']*'|[^\s#'"{\[][^:#]*?)\s*:)(?:\s|$)`)
+	yamlItemPattern      = regexp.MustCompile(`^(\s*)-(?:\s|$)`)
+	propertiesKeyPattern = regexp.MustCompile(`^\s*((?:\\.|[^\s=:\\])+)\s*=`)
+)
+
+type pathEntry struct {
+	indent int
+	name   string
+	item   bool
+	next   int
+}
+
+// Line describes a single line of a YAML or properties file as seen by the PathTracker.
+type Line struct {
+	// Path is the property path of the value on the line
+	Path string
+	// HasValue reports whether the line starts a key or a list item
+	HasValue bool
+	// Value is the scalar value without quotes
+	Value string
+	// Column is the 1-based column of the value including its opening quote
+	Column int
+	// Length is the number of bytes of the raw value including its quotes
+	Length int
+	// Quote is the quote character of the value, 0 for plain values
+	Quote byte
+	// Comment is the trailing or whole line comment without the leading #
+	Comment string
+}
+
+// PathTracker follows the structure of a YAML or properties file line by line to
+// derive the property path of the values found on the current line.
+// It is a best effort heuristic, it does not validate the document.
+type PathTracker struct {
+	stack []pathEntry
+}
+
+func NewPathTracker() *PathTracker {
+	return &PathTracker{}
+}
+
+// Next processes the line and returns its property path and value.
+func (t *PathTracker) Next(line string) Line {
+	line = strings.TrimRight(line, "\r\n")
+	trimmed := strings.TrimSpace(line)
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+	if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "!") {
+		return Line{Comment: strings.TrimSpace(trimmed[1:])}
+	}
+	if trimmed == "" {
+		return Line{}
+	}
+	if trimmed == "---" || trimmed == "..." {
+		t.stack = t.stack[:0]
+		return Line{}
+	}
+	if m := propertiesKeyPattern.FindStringSubmatch(line); m != nil && indent == 0 {
+		t.stack = t.stack[:0]
+		value := strings.TrimLeft(line[len(m[0]):], " \t")
+		column := len(line) - len(value) + 1
+		value = strings.TrimRight(value, " \t")
+		return Line{
+			Path:     strings.ReplaceAll(m[1], `\`, ""),
+			HasValue: true,
+			Value:    value,
+			Column:   column,
+			Length:   len(value),
+		}
+	}
+	item := false
+	// list items, possibly nested like "- - value" or "- key: value"
+	for {
+		m := yamlItemPattern.FindStringSubmatch(line)
+		if m == nil {
+			break
+		}
+		dash := len(m[1])
+		t.popDeeper(dash)
+		if n := len(t.stack); n != 0 && t.stack[n-1].item && t.stack[n-1].indent == dash {
+			t.stack = t.stack[:n-1]
+		}
+		index := 0
+		if n := len(t.stack); n != 0 {
+			index = t.stack[n-1].next
+			t.stack[n-1].next++
+		}
+		t.stack = append(t.stack, pathEntry{indent: dash, name: "[" + strconv.Itoa(index) + "]", item: true})
+		// replace the dash with a space to keep the columns
+		line = line[:dash] + " " + line[dash+1:]
+		item = true
+	}
+	m := yamlKeyPattern.FindStringSubmatch(line)
+	if m == nil {
+		if !item {
+			return Line{Path: t.path()}
+		}
+		return t.scalar(line, len(line)-len(strings.TrimLeft(line, " \t")))
+	}
+	keyIndent := len(m[1])
+	t.popDeeper(keyIndent - 1)
+	t.stack = append(t.stack, pathEntry{indent: keyIndent, name: strings.Trim(m[2], `"'`)})
+	start := len(m[0])
+	for start < len(line) && (line[start] == ' ' || line[start] == '\t') {
+		start++
+	}
+	return t.scalar(line, start)
+}
+
+// scalar parses the YAML value starting at the byte offset of the line.
+func (t *PathTracker) scalar(line string, start int) Line {
+	result := Line{Path: t.path(), HasValue: true, Column: start + 1}
+	rest := line[start:]
+	if rest == "" {
+		return result
+	}
+	switch quote := rest[0]; quote {
+	case '\'', '"':
+		result.Quote = quote
+		var sb strings.Builder
+		i := 1
+		for ; i < len(rest); i++ {
+			c := rest[i]
+			if quote == '\'' && c == '\'' {
+				if i+1 < len(rest) && rest[i+1] == '\'' {
+					sb.WriteByte(c)
+					i++
+					continue
+				}
+				break
+			}
+			if quote == '"' && c == '\\' && i+1 < len(rest) {
+				i++
+				sb.WriteByte(rest[i])
+				continue
+			}
+			if quote == '"' && c == '"' {
+				break
+			}
+			sb.WriteByte(c)
+		}
+		result.Value = sb.String()
+		result.Length = len(rest)
+		if i < len(rest) {
+			result.Length = i + 1
+			result.Comment = comment(rest[i+1:])
+		}
+	case '#':
+		result.Comment = strings.TrimSpace(rest[1:])
+	default:
+		if i := strings.Index(rest, " #"); i != -1 {
+			result.Comment = strings.TrimSpace(rest[i+2:])
+			rest = rest[:i]
+		}
+		result.Value = strings.TrimRight(rest, " \t")
+		result.Length = len(result.Value)
+	}
+	return result
+}
+
+func comment(rest string) string {
+	if i := strings.IndexByte(rest, '#'); i != -1 {
+		return strings.TrimSpace(rest[i+1:])
+	}
+	return ""
+}
+
+func (t *PathTracker) popDeeper(indent int) {
+	for len(t.stack) != 0 && t.stack[len(t.stack)-1].indent > indent {
+		t.stack = t.stack[:len(t.stack)-1]
+	}
+}
+
+func (t *PathTracker) path() string {
+	var sb strings.Builder
+	for _, e := range t.stack {
+		if sb.Len() != 0 && !e.item {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(e.name)
+	}
+	return sb.String()
+}
@@ -0,0 +1,397 @@
+package properties
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the serialization format of a property set.
+type Format string
+
+const (
+	FormatYAML       Format = "yaml"
+	FormatProperties Format = "properties"
+	FormatJSON       Format = "json"
+	FormatEnv        Format = "env"
+)
+
+// Formats lists the supported output formats.
+var Formats = []Format{FormatYAML, FormatProperties, FormatJSON, FormatEnv}
+
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(value) {
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "properties", "props":
+		return FormatProperties, nil
+	case "json":
+		return FormatJSON, nil
+	case "env":
+		return FormatEnv, nil
+	}
+	return "", fmt.Errorf("unsupported format '%s', expected one of %v", value, Formats)
+}
+
+// Properties is a flat set of Spring properties keyed by the property path
+// e.g. "spring.datasource.url" or "servers[0].host".
+type Properties map[string]interface{}
+
+// Keys returns the property paths in sorted order.
+func (p Properties) Keys() []string {
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Lookup returns the key and value of the property path. Names are matched relaxed as by Spring's binding, case,
+// dashes and underscores are ignored and list indices may be written as servers.0.host. The environment variable
+// form like SPRING_DATASOURCE_URL is matched as well.
+func (p Properties) Lookup(path string) (string, interface{}, bool) {
+	if value, ok := p[path]; ok {
+		return path, value, true
+	}
+	canonical, err := canonicalPath(path)
+	if err != nil {
+		return "", nil, false
+	}
+	for _, key := range p.Keys() {
+		if c, err := canonicalPath(key); (err == nil && c == canonical) || EnvName(key) == path {
+			return key, p[key], true
+		}
+	}
+	return "", nil, false
+}
+
+// canonicalPath returns the path in the lower case form without dashes and underscores.
+func canonicalPath(path string) (string, error) {
+	segments, err := ParsePath(path)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, len(segments))
+	for i, segment := range segments {
+		if index, err := strconv.Atoi(segment.Key); err == nil && !segment.IsIndex() {
+			segment = Segment{Index: index}
+		}
+		if segment.IsIndex() {
+			parts[i] = "[" + strconv.Itoa(segment.Index) + "]"
+		} else {
+			parts[i] = strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(segment.Key))
+		}
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// Flatten converts a nested structure into properties the same way Spring's YamlProcessor does.
+func Flatten(value map[string]interface{}) Properties {
+	result := make(Properties)
+	flattenMap(result, "", value)
+	return result
+}
+
+func flattenMap(result Properties, path string, value map[string]interface{}) {
+	for key, v := range value {
+		if path != "" {
+			if strings.HasPrefix(key, "[") {
+				key = path + key
+			} else {
+				key = path + "." + key
+			}
+		}
+		flattenValue(result, key, v)
+	}
+}
+
+func flattenValue(result Properties, key string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		flattenMap(result, key, v)
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = e
+		}
+		flattenMap(result, key, m)
+	case []interface{}:
+		if len(v) == 0 {
+			result[key] = ""
+		}
+		for i, e := range v {
+			flattenValue(result, key+"["+strconv.Itoa(i)+"]", e)
+		}
+	case nil:
+		result[key] = ""
+	default:
+		result[key] = v
+	}
+}
+
+// Segment is a single element of a property path, either a map key or a list index.
+type Segment struct {
+	Key   string
+	Index int
+}
+
+// IsIndex reports whether the segment addresses a list element.
+func (s Segment) IsIndex() bool {
+	return s.Index >= 0
+}
+
+// ParsePath splits a property path like "servers[0].host" or "map[a.b].c" into segments.
+func ParsePath(path string) ([]Segment, error) {
+	var segments []Segment
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("property path '%s' has unclosed bracket", path)
+			}
+			inner := path[i+1 : i+end]
+			if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+				segments = append(segments, Segment{Index: index})
+			} else {
+				segments = append(segments, Segment{Key: inner, Index: -1})
+			}
+			i += end + 1
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end == -1 {
+				end = len(path) - i
+			}
+			segments = append(segments, Segment{Key: path[i : i+end], Index: -1})
+			i += end
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("property path '%s' is empty", path)
+	}
+	return segments, nil
+}
+
+// Unflatten converts the properties into a nested structure of maps and lists.
+func (p Properties) Unflatten() (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	for _, key := range p.Keys() {
+		segments, err := ParsePath(key)
+		if err != nil {
+			return nil, err
+		}
+		if segments[0].IsIndex() {
+			return nil, fmt.Errorf("property path '%s' cannot start with an index", key)
+		}
+		if err := setValue(root, segments, p[key]); err != nil {
+			return nil, errors.Wrapf(err, "property '%s'", key)
+		}
+	}
+	return root, nil
+}
+
+func setValue(root map[string]interface{}, segments []Segment, value interface{}) error {
+	var (
+		container interface{} = root
+		set       func(interface{})
+	)
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		var next interface{}
+		switch c := container.(type) {
+		case map[string]interface{}:
+			if segment.IsIndex() {
+				return errors.New("index used on a map")
+			}
+			key := segment.Key
+			if strings.ContainsAny(key, ".[]") {
+				// keep keys with dots as a single key, the way Spring expects them in YAML
+				key = "[" + key + "]"
+			}
+			next = c[key]
+			set = func(v interface{}) { c[key] = v }
+		case []interface{}:
+			if !segment.IsIndex() {
+				return errors.New("key used on a list")
+			}
+			for len(c) <= segment.Index {
+				c = append(c, nil)
+			}
+			// the slice may have grown, store it back in its parent
+			set(c)
+			next = c[segment.Index]
+			index := segment.Index
+			set = func(v interface{}) { c[index] = v }
+		default:
+			return errors.New("conflicts with a scalar value")
+		}
+		if last {
+			switch next.(type) {
+			case map[string]interface{}, []interface{}:
+				return errors.New("conflicts with a nested value")
+			}
+			set(value)
+			return nil
+		}
+		if next == nil {
+			if segments[i+1].IsIndex() {
+				next = []interface{}{}
+			} else {
+				next = make(map[string]interface{})
+			}
+			set(next)
+		}
+		container = next
+	}
+	return nil
+}
+
+// Write serializes the properties in the given format.
+func Write(w io.Writer, props Properties, format Format) error {
+	switch format {
+	case FormatYAML:
+		return WriteYAML(w, props)
+	case FormatProperties:
+		return WriteProperties(w, props)
+	case FormatJSON:
+		return WriteJSON(w, props)
+	case FormatEnv:
+		return WriteEnv(w, props)
+	}
+	return fmt.Errorf("unsupported format '%s'", format)
+}
+
+func WriteYAML(w io.Writer, props Properties) error {
+	tree, err := props.Unflatten()
+	if err != nil {
+		return err
+	}
+	if len(tree) == 0 {
+		_, err = io.WriteString(w, "{}\n")
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err = enc.Encode(tree); err != nil {
+		return errors.Wrap(err, "yaml encoding error")
+	}
+	return enc.Close()
+}
+
+func WriteJSON(w io.Writer, props Properties) error {
+	tree, err := props.Unflatten()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return errors.Wrap(enc.Encode(tree), "json encoding error")
+}
+
+func WriteProperties(w io.Writer, props Properties) error {
+	wr := bufio.NewWriter(w)
+	for _, key := range props.Keys() {
+		_, _ = wr.WriteString(escapeProperty(key, true))
+		_, _ = wr.WriteString("=")
+		_, _ = wr.WriteString(escapeProperty(ToString(props[key]), false))
+		_, _ = wr.WriteString("\n")
+	}
+	return errors.Wrap(wr.Flush(), "writing flush error")
+}
+
+func WriteEnv(w io.Writer, props Properties) error {
+	wr := bufio.NewWriter(w)
+	for _, key := range props.Keys() {
+		_, _ = wr.WriteString(EnvName(key))
+		_, _ = wr.WriteString("=")
+		_, _ = wr.WriteString(quoteEnv(ToString(props[key])))
+		_, _ = wr.WriteString("\n")
+	}
+	return errors.Wrap(wr.Flush(), "writing flush error")
+}
+
+// ToString formats a property value the way it appears in a properties file.
+func ToString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// EnvName converts a property path to the environment variable name matched by Spring's relaxed binding
+// e.g. "spring.data-source.servers[0].url" becomes "SPRING_DATASOURCE_SERVERS_0_URL".
+func EnvName(path string) string {
+	var sb strings.Builder
+	for _, r := range path {
+		switch {
+		case r == '-':
+		case r == '.' || r == '[':
+			sb.WriteByte('_')
+		case r == ']':
+		case r >= 'a' && r <= 'z':
+			sb.WriteRune(r - 'a' + 'A')
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
+
+func quoteEnv(value string) string {
+	if value == "" {
+		return value
+	}
+	for _, r := range value {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-./:@%+,=", r)) {
+			return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+		}
+	}
+	return value
+}
+
+// escapeProperty escapes a key or value as java.util.Properties.store does.
+func escapeProperty(value string, key bool) string {
+	var sb strings.Builder
+	for i, r := range value {
+		switch r {
+		case ' ':
+			if i == 0 || key {
+				sb.WriteByte('\\')
+			}
+			sb.WriteRune(r)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\\', '=', ':', '#', '!':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,226 @@
+package properties
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	props := Properties{
+		"spring.datasource.url":      "jdbc:postgresql://db/app",
+		"spring.datasource.password": "it's secret",
+		"servers[0].host":            "a",
+		"servers[1].host":            "b",
+		"map[a.b]":                   "dotted",
+		"enabled":                    true,
+		"port":                       float64(8080),
+	}
+	tt := []struct {
+		name   string
+		format Format
+
+		expected string
+	}{
+		{name: "YAML",
+			format: FormatYAML,
+			expected: `enabled: true
+map:
+  '[a.b]': dotted
+port: 8080
+servers:
+  - host: a
+  - host: b
+spring:
+  datasource:
+    password: it's secret
+    url: jdbc:postgresql://db/app
+`},
+		{name: "Properties",
+			format: FormatProperties,
+			expected: `enabled=true
+map[a.b]=dotted
+port=8080
+servers[0].host=a
+servers[1].host=b
+spring.datasource.password=it's secret
+spring.datasource.url=jdbc\:postgresql\://db/app
+`},
+		{name: "Env",
+			format: FormatEnv,
+			expected: `ENABLED=true
+MAP_A_B=dotted
+PORT=8080
+SERVERS_0_HOST=a
+SERVERS_1_HOST=b
+SPRING_DATASOURCE_PASSWORD='it'\''s secret'
+SPRING_DATASOURCE_URL=jdbc:postgresql://db/app
+`},
+		{name: "JSON",
+			format: FormatJSON,
+			expected: `{
+  "enabled": true,
+  "map": {
+    "[a.b]": "dotted"
+  },
+  "port": 8080,
+  "servers": [
+    {
+      "host": "a"
+    },
+    {
+      "host": "b"
+    }
+  ],
+  "spring": {
+    "datasource": {
+      "password": "it's secret",
+      "url": "jdbc:postgresql://db/app"
+    }
+  }
+}
+`},
+	}
+	for _, tc := range tt {
+		buf := new(bytes.Buffer)
+		if err := Write(buf, props, tc.format); err != nil {
+			t.Fatalf("%s: write error: %v", tc.name, err)
+		}
+		if actual := buf.String(); actual != tc.expected {
+			t.Errorf("%s: values differ: expected %v, actual %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestFlattenRoundTrip(t *testing.T) {
+	props := Properties{
+		"a.b":        "x",
+		"a.c[0]":     "y",
+		"a.c[1].d":   "z",
+		"list[0][0]": "nested",
+	}
+	tree, err := props.Unflatten()
+	if err != nil {
+		t.Fatalf("unflatten error: %v", err)
+	}
+	actual := Flatten(tree)
+	if len(actual) != len(props) {
+		t.Fatalf("Values differ: expected %v, actual %v", props, actual)
+	}
+	for key, value := range props {
+		if actual[key] != value {
+			t.Errorf("Values differ for %s: expected %v, actual %v", key, value, actual[key])
+		}
+	}
+}
+
+func TestUnflattenConflict(t *testing.T) {
+	props := Properties{
+		"a":   "x",
+		"a.b": "y",
+	}
+	if _, err := props.Unflatten(); err == nil {
+		t.Errorf("Expected conflict error")
+	}
+}
+
+func TestPathTracker(t *testing.T) {
+	input := `# comment
+spring:
+  datasource:
+    url: jdbc:postgresql://db/app
+    "password": secret
+  profiles: dev
+servers:
+  - host: a
+    port: 1
+  - host: b
+list:
+- x
+- y
+---
+top.level=value
+other: [a, b]
+`
+	expected := []string{"", "spring", "spring.datasource", "spring.datasource.url", "spring.datasource.password", "spring.profiles",
+		"servers", "servers[0].host", "servers[0].port", "servers[1].host", "list", "list[0]", "list[1]", "", "top.level", "other"}
+
+	paths := NewPathTracker()
+	for i, line := range strings.Split(strings.TrimSuffix(input, "\n"), "\n") {
+		if actual := paths.Next(line).Path; actual != expected[i] {
+			t.Errorf("Line %d paths differ: expected %v, actual %v", i+1, expected[i], actual)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	tt := []struct {
+		name     string
+		format   Format
+		input    string
+		expected Properties
+	}{
+		{
+			name:     "YAML documents",
+			format:   FormatYAML,
+			input:    "a:\n  b: 1\n  c: [x, y]\n---\na:\n  b: 2\n",
+			expected: Properties{"a.b": 2, "a.c[0]": "x", "a.c[1]": "y"},
+		},
+		{
+			name:     "JSON",
+			format:   FormatJSON,
+			input:    `{"a": {"b": "x", "c": [true]}}`,
+			expected: Properties{"a.b": "x", "a.c[0]": true},
+		},
+		{
+			name:     "Properties",
+			format:   FormatProperties,
+			input:    "# comment\n! comment\na.b = x\na.c:y\na.d z\nlong=a\\\n    b\\\\\nescaped\\ key=\\u0041\\tB\nempty\n",
+			expected: Properties{"a.b": "x", "a.c": "y", "a.d": "z", "long": `ab\`, "escaped key": "A\tB", "empty": ""},
+		},
+		{
+			name:     "Properties documents",
+			format:   FormatProperties,
+			input:    "a=1\nb=1\n#---\na=2\n!---\r\nc=3\n",
+			expected: Properties{"a": "2", "b": "1", "c": "3"},
+		},
+	}
+	for _, tc := range tt {
+		actual, err := Read(strings.NewReader(tc.input), tc.format)
+		if err != nil {
+			t.Fatalf("%s: read error: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: properties differ: expected %v, actual %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	props := Properties{
+		"spring.datasource.max-pool-size": 10,
+		"spring.datasource.password":      "secret",
+		"servers[1].host":                 "b",
+	}
+	tt := []struct {
+		path     string
+		expected string
+	}{
+		{path: "spring.datasource.password", expected: "spring.datasource.password"},
+		{path: "spring.datasource.maxPoolSize", expected: "spring.datasource.max-pool-size"},
+		{path: "spring.dataSource.max_pool_size", expected: "spring.datasource.max-pool-size"},
+		{path: "SPRING_DATASOURCE_PASSWORD", expected: "spring.datasource.password"},
+		{path: "servers[1].host", expected: "servers[1].host"},
+		{path: "servers.1.host", expected: "servers[1].host"},
+		{path: "servers[0].host"},
+		{path: "spring.datasource"},
+		{path: "a[b"},
+	}
+	for _, tc := range tt {
+		key, _, ok := props.Lookup(tc.path)
+		if ok != (tc.expected != "") || key != tc.expected {
+			t.Errorf("%s: expected %q, actual %q %v", tc.path, tc.expected, key, ok)
+		}
+	}
+}
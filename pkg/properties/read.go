@@ -0,0 +1,184 @@
+package properties
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// FormatOf returns the format of the file by its extension, YAML for unknown extensions.
+func FormatOf(fileName string) Format {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".properties":
+		return FormatProperties
+	case ".json":
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// Read parses the YAML, JSON or properties content, the properties of later YAML documents override the earlier ones.
+func Read(r io.Reader, format Format) (Properties, error) {
+	documents, err := ReadDocuments(r, format)
+	if err != nil {
+		return nil, err
+	}
+	result := make(Properties)
+	for _, document := range documents {
+		for key, value := range document {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+// ReadDocuments parses each document of a multi-document YAML or properties file separately, JSON has a single document.
+// The documents of a properties file are separated by #--- or !--- lines as in Spring Boot.
+func ReadDocuments(r io.Reader, format Format) ([]Properties, error) {
+	switch format {
+	case FormatYAML:
+		var result []Properties
+		dec := yaml.NewDecoder(r)
+		for {
+			var document map[string]interface{}
+			err := dec.Decode(&document)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, errors.Wrap(err, "yaml parsing error")
+			}
+			result = append(result, Flatten(document))
+		}
+		return result, nil
+	case FormatJSON:
+		var document map[string]interface{}
+		if err := json.NewDecoder(r).Decode(&document); err != nil {
+			return nil, errors.Wrap(err, "json parsing error")
+		}
+		return []Properties{Flatten(document)}, nil
+	case FormatProperties:
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "properties reading error")
+		}
+		var result []Properties
+		for _, document := range documentSeparator.Split(string(content), -1) {
+			props, err := readProperties(strings.NewReader(document))
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, props)
+		}
+		return result, nil
+	}
+	return nil, errors.Errorf("unsupported input format '%s'", format)
+}
+
+var documentSeparator = regexp.MustCompile(`(?m)^[#!]---[ \t]*\r?$`)
+
+// readProperties parses the content the same way java.util.Properties.load does.
+func readProperties(r io.Reader) (Properties, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "properties reading error")
+	}
+	result := make(Properties)
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	scanner.Buffer(make([]byte, 64*1024), len(content)+1)
+	var logical strings.Builder
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical.Len() == 0 && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+		if continued(line) {
+			logical.WriteString(line[:len(line)-1])
+			continue
+		}
+		logical.WriteString(line)
+		key, value := splitProperty(logical.String())
+		result[unescapeProperty(key)] = unescapeProperty(value)
+		logical.Reset()
+	}
+	if logical.Len() != 0 {
+		key, value := splitProperty(logical.String())
+		result[unescapeProperty(key)] = unescapeProperty(value)
+	}
+	return result, errors.Wrap(scanner.Err(), "properties reading error")
+}
+
+// continued reports whether the line ends with an odd number of backslashes.
+func continued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+func splitProperty(line string) (string, string) {
+	i := 0
+	for ; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) != -1 {
+			break
+		}
+	}
+	if i >= len(line) {
+		return line, ""
+	}
+	key, rest := line[:i], strings.TrimLeft(line[i:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return key, rest
+}
+
+func unescapeProperty(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\\' || i+1 == len(value) {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		switch value[i] {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'u':
+			if i+4 < len(value) {
+				if r, err := strconv.ParseUint(value[i+1:i+5], 16, 32); err == nil {
+					sb.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			sb.WriteByte('u')
+		default:
+			sb.WriteByte(value[i])
+		}
+	}
+	return sb.String()
+}
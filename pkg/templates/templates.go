@@ -0,0 +1,88 @@
+// Package templates renders Go text/template files with the decrypted properties of Spring configuration files,
+// e.g. for nginx configs or property files of applications which are not Spring applications.
+package templates
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+	"github.com/pkg/errors"
+)
+
+// ValueDecryptor decrypts a single property value, values which are not encrypted are returned unchanged.
+type ValueDecryptor interface {
+	DecryptValue(value string) (string, error)
+}
+
+// Renderer renders templates with the properties as data, the nested properties are the dot of the template
+// and the functions prop, decrypt, b64enc and quote are available.
+type Renderer struct {
+	props          properties.Properties
+	data           map[string]interface{}
+	valueDecryptor ValueDecryptor
+}
+
+// NewRenderer creates a renderer of the decrypted properties, valueDecryptor is used by the decrypt function.
+func NewRenderer(props properties.Properties, valueDecryptor ValueDecryptor) (*Renderer, error) {
+	data, err := props.Unflatten()
+	if err != nil {
+		return nil, err
+	}
+	return &Renderer{props: props, data: data, valueDecryptor: valueDecryptor}, nil
+}
+
+// ReadSource parses the configuration file and decrypts its values.
+func ReadSource(fileName string, content []byte, valueDecryptor ValueDecryptor) (properties.Properties, error) {
+	props, err := properties.Read(bytes.NewReader(content), properties.FormatOf(fileName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s", fileName)
+	}
+	for key, value := range props {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if props[key], err = valueDecryptor.DecryptValue(s); err != nil {
+			return nil, &decryptor.DecryptError{File: fileName, Path: key, Err: err}
+		}
+	}
+	return props, nil
+}
+
+// Render executes the template text, a missing property is an error.
+func (r *Renderer) Render(w io.Writer, name, text string) error {
+	t, err := template.New(name).Option("missingkey=error").Funcs(r.funcs()).Parse(text)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, r.data)
+}
+
+func (r *Renderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		// prop returns the property by its Spring path like spring.datasource.password or servers[0].host
+		"prop": func(path string) (string, error) {
+			value, ok := r.props[path]
+			if !ok {
+				return "", fmt.Errorf("property '%s' not found", path)
+			}
+			return properties.ToString(value), nil
+		},
+		// decrypt decrypts a {cipher} value of the template itself
+		"decrypt": func(value string) (string, error) {
+			return r.valueDecryptor.DecryptValue(strings.TrimSpace(value))
+		},
+		"b64enc": func(value interface{}) string {
+			return base64.StdEncoding.EncodeToString([]byte(properties.ToString(value)))
+		},
+		"quote": func(value interface{}) string {
+			return fmt.Sprintf("%q", properties.ToString(value))
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/encryptor"
+)
+
+func TestRender(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate key error: %v", err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	valueDecryptor, err := decryptor.NewValueDecryptor(privateKey)
+	if err != nil {
+		t.Fatalf("create value decryptor error: %v", err)
+	}
+	valueEncryptor, err := encryptor.NewValueEncryptor(privateKey)
+	if err != nil {
+		t.Fatalf("create value encryptor error: %v", err)
+	}
+	password, err := valueEncryptor.EncryptValue(`s3"cr3t`)
+	if err != nil {
+		t.Fatalf("encrypt error: %v", err)
+	}
+	token, err := valueEncryptor.EncryptValue("token")
+	if err != nil {
+		t.Fatalf("encrypt error: %v", err)
+	}
+
+	source := "spring:\n  datasource:\n    url: jdbc:h2\n    password: '" + password + "'\nservers:\n  - host: a\n  - host: b\n"
+	props, err := ReadSource("application.yml", []byte(source), valueDecryptor)
+	if err != nil {
+		t.Fatalf("read source error: %v", err)
+	}
+	_, err = ReadSource("application.properties", []byte("a.b={cipher}AQ==\n"), valueDecryptor)
+	if err == nil || err.Error() != "application.properties property 'a.b': data too short to read session key length" {
+		t.Errorf("Unexpected error %v", err)
+	}
+	renderer, err := NewRenderer(props, valueDecryptor)
+	if err != nil {
+		t.Fatalf("create renderer error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		err      string
+	}{
+		{name: "prop", text: `{{ prop "spring.datasource.password" }} {{ prop "servers[1].host" }}`, expected: `s3"cr3t b`},
+		{name: "data", text: `{{ .spring.datasource.url }}{{ range .servers }} {{ .host }}{{ end }}`, expected: "jdbc:h2 a b"},
+		{name: "functions", text: `{{ prop "spring.datasource.password" | quote }} {{ "user" | b64enc }}`, expected: `"s3\"cr3t" dXNlcg==`},
+		{name: "decrypt", text: `{{ decrypt "` + token + `" }}`, expected: "token"},
+		{name: "missing prop", text: `{{ prop "missing" }}`, err: `template: test:1:3: executing "test" at <prop "missing">: error calling prop: property 'missing' not found`},
+		{name: "missing key", text: `{{ .missing }}`, err: `template: test:1:3: executing "test" at <.missing>: map has no entry for key "missing"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := renderer.Render(&out, "test", tc.text)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected error %q, actual %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("render error: %v", err)
+			}
+			if out.String() != tc.expected {
+				t.Errorf("Expected %q, actual %q", tc.expected, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/keys"
+)
+
+func runPubkey(args []string) {
+	fs := flag.NewFlagSet("pubkey", flag.ExitOnError)
+	var (
+		keyFile        = fs.String("k", "", keyFlagUsage)
+		passphraseFile = fs.String("passphrase-file", "", "The file with the passphrase of an encrypted private key.")
+		format         = fs.String("format", string(keys.FormatPKIX), "The public key format: pkix or pkcs1.")
+		output         = fs.String("o", "-", `The file to write the public key to. Use '-' for stdout.`)
+	)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s pubkey [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	keyFormat, err := keys.ParseFormat(*format)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	key, err := readPrivateKey(*keyFile, *passphraseFile)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	publicKey, err := keys.EncodePublicKey(&key.PublicKey, keyFormat)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	w, closeOutput, err := openOutput(*output, true)
+	if err != nil {
+		exitOnError("output open file error: %v", err)
+	}
+	if _, err = w.Write(publicKey); err != nil {
+		exitOnError("public key write error: %v", err)
+	}
+	closeOutput()
+}
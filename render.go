@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/configdata"
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+)
+
+func runRender(args []string) {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	var (
+		profiles = fs.String("profiles", "", "The comma separated list of active profiles. If empty spring.profiles.active of the files or the default profile is used.")
+		format   = fs.String("format", string(properties.FormatYAML), "The output format: yaml, properties, json or env.")
+		output   = fs.String("o", "-", `The file to write the result to. Use '-' for stdout.`)
+		keyFile  = fs.String("k", "", keyFlagUsage)
+		config   = fs.String("config", "", configFlagUsage)
+		keyring  stringsFlag
+	)
+	fs.Var(&keyring, "keyring", keyringFlagUsage)
+	kmsOptions := addKMSFlags(fs)
+	jasyptOptions := addJasyptFlags(fs)
+	resolveOptions := addResolveFlags(fs)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s render [flags] <config directory> [application]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	outputFormat, err := properties.ParseFormat(*format)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+
+	valueDecryptors, err := newValueDecryptors(fs, *config, fs.Arg(0), *keyFile, keyring, kmsOptions, jasyptOptions)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	loader, err := configdata.NewLoader(configdata.DirReadFile(fs.Arg(0)))
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	env, err := loader.Load(fs.Arg(1), configdata.SplitProfiles(*profiles))
+	if err != nil {
+		exitOnError("render error: %v", err)
+	}
+	if err = env.Decrypt(decryptor.NewSchemeDecryptor(valueDecryptors.schemes)); err != nil {
+		exitOnError("decrypt error: %v", err)
+	}
+
+	props, err := resolveOptions.resolve(env.Properties())
+	if err != nil {
+		exitOnError("resolve error: %v", err)
+	}
+
+	out, closeOutput, err := openOutput(*output, true)
+	if err != nil {
+		exitOnError("output open file error: %v", err)
+	}
+	defer closeOutput()
+	if err = properties.Write(out, props, outputFormat); err != nil {
+		exitOnError("output write error: %v", err)
+	}
+}
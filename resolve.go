@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/placeholders"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+)
+
+type resolveFlags struct {
+	enabled *bool
+	strict  *bool
+}
+
+func addResolveFlags(fs *flag.FlagSet) *resolveFlags {
+	return &resolveFlags{
+		enabled: fs.Bool("resolve", false, "Resolve the ${name:default} placeholders of the decrypted values with other properties and environment variables."),
+		strict:  fs.Bool("resolve-strict", false, "Resolve the placeholders and fail on those which cannot be resolved and have no default."),
+	}
+}
+
+// resolve resolves the placeholders of the properties if -resolve or -resolve-strict is set.
+func (f *resolveFlags) resolve(props properties.Properties) (properties.Properties, error) {
+	if !*f.enabled && !*f.strict {
+		return props, nil
+	}
+	return placeholders.NewResolver(placeholders.WithStrict(*f.strict)).Resolve(props)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/encryptor"
+)
+
+// stringsFlag is a flag which can be repeated.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func runRotate(args []string) {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	var (
+		keyFiles     stringsFlag
+		newKeyFile   = fs.String("new-key", "", "The file with the new RSA public key. If it is a private key, the re-encrypted values are verified with it.")
+		verifyKey    = fs.String("verify-key", "", "The file with the new RSA private key used to verify the re-encrypted values.")
+		keyAlias     = fs.String("key-alias", "", "The {key:alias} prefix of the re-encrypted values.")
+		salt         = fs.String("salt", decryptor.DefaultSalt, "The hex encoded salt of the re-encrypted values.")
+		algorithm    = fs.String("algorithm", string(decryptor.RsaAlgorithmDefault), "The RSA algorithm of the re-encrypted values: DEFAULT or OAEP.")
+		strong       = fs.Bool("strong", false, "Use AES/GCM for the re-encrypted values.")
+		oldSalt      = fs.String("old-salt", decryptor.DefaultSalt, "The hex encoded salt of the current values.")
+		oldAlgorithm = fs.String("old-algorithm", string(decryptor.RsaAlgorithmDefault), "The RSA algorithm of the current values: DEFAULT or OAEP.")
+		oldStrong    = fs.Bool("old-strong", false, "The current values use AES/GCM.")
+		dryRun       = fs.Bool("dry-run", false, "Only report the values which would be rotated, do not write the files.")
+	)
+	fs.Var(&keyFiles, "k", "The old RSA private key source, a file or a key source URI like env://NAME, can be repeated. If not set the key is read from environment variable "+defaultEnvEncryptKey+" / "+defaultEnvEncryptKeyBase64)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s rotate -new-key <file> [flags] <file or directory>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 || *newKeyFile == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	oldRsaAlgorithm, err := decryptor.ParseRsaAlgorithm(*oldAlgorithm)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	newRsaAlgorithm, err := decryptor.ParseRsaAlgorithm(*algorithm)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+
+	if len(keyFiles) == 0 {
+		keyFiles = stringsFlag{""}
+	}
+	var decryptors []*decryptor.ValueDecryptor
+	for _, keyFile := range keyFiles {
+		key, err := readKey(keyFile)
+		if err != nil {
+			exitOnError("%v", err)
+		}
+		valueDecryptor, err := decryptor.NewValueDecryptor(key, decryptor.WithSalt(*oldSalt), decryptor.WithAlgorithm(oldRsaAlgorithm), decryptor.WithStrong(*oldStrong))
+		if err != nil {
+			exitOnError("create decryptor error: %v", err)
+		}
+		decryptors = append(decryptors, valueDecryptor)
+	}
+
+	newKey, err := ioutil.ReadFile(*newKeyFile)
+	if err != nil {
+		exitOnError("new key file reading error: %v", err)
+	}
+	valueEncryptor, err := encryptor.NewValueEncryptor(newKey, encryptor.WithKeyAlias(*keyAlias), encryptor.WithSalt(*salt), encryptor.WithAlgorithm(newRsaAlgorithm), encryptor.WithStrong(*strong))
+	if err != nil {
+		exitOnError("create encryptor error: %v", err)
+	}
+	verifierOptions := []decryptor.ValueDecryptorOption{decryptor.WithSalt(*salt), decryptor.WithAlgorithm(newRsaAlgorithm), decryptor.WithStrong(*strong)}
+	var verifier *decryptor.ValueDecryptor
+	if *verifyKey != "" {
+		key, err := ioutil.ReadFile(*verifyKey)
+		if err != nil {
+			exitOnError("verify key file reading error: %v", err)
+		}
+		if verifier, err = decryptor.NewValueDecryptor(key, verifierOptions...); err != nil {
+			exitOnError("create verifier error: %v", err)
+		}
+	} else if _, err := decryptor.ParsePrivateKey(newKey); err == nil {
+		if verifier, err = decryptor.NewValueDecryptor(newKey, verifierOptions...); err != nil {
+			exitOnError("create verifier error: %v", err)
+		}
+	}
+	rotator := encryptor.NewRotator(decryptors, valueEncryptor, verifier)
+
+	var paths []string
+	err = walkFiles(fs.Args(), func(path string, content []byte) error {
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		exitOnError("rotate error: %v", err)
+	}
+	// every file is rotated before any is written, so a failure does not leave a partially rotated tree
+	rotated, err := rotator.RotateFiles(paths)
+	if err != nil {
+		exitOnError("rotate error: %v", err)
+	}
+	total := 0
+	for _, file := range rotated {
+		total += file.Count
+	}
+	if !*dryRun {
+		if err = encryptor.WriteRotatedFiles(rotated); err != nil {
+			exitOnError("rotate write error: %v", err)
+		}
+	}
+	for _, file := range rotated {
+		if *dryRun {
+			_, _ = fmt.Fprintf(os.Stderr, "%s: would rotate %d values\n", file.Path, file.Count)
+		} else {
+			_, _ = fmt.Fprintf(os.Stderr, "%s: rotated %d values\n", file.Path, file.Count)
+		}
+	}
+	if verifier == nil && !*dryRun {
+		_, _ = fmt.Fprintln(os.Stderr, "warning: the re-encrypted values were not verified, use -verify-key to verify them")
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "%d values in %d files\n", total, len(rotated))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grepplabs/spring-config-decryptor/pkg/decryptor"
+	"github.com/grepplabs/spring-config-decryptor/pkg/properties"
+	"github.com/grepplabs/spring-config-decryptor/pkg/templates"
+)
+
+// templateExtensions are removed from the template file names to name the output files.
+var templateExtensions = []string{".tmpl", ".gotmpl", ".tpl"}
+
+func runTemplate(args []string) {
+	fs := flag.NewFlagSet("template", flag.ExitOnError)
+	var (
+		output  = fs.String("o", "-", `The file to write the result to or the directory for several templates. Use '-' for stdout.`)
+		keyFile = fs.String("k", "", keyFlagUsage)
+		config  = fs.String("config", "", configFlagUsage)
+		sources stringsFlag
+		keyring stringsFlag
+	)
+	fs.Var(&sources, "source", "The YAML, properties or JSON file with the properties of the templates, can be repeated. Later sources override earlier ones.")
+	fs.Var(&keyring, "keyring", keyringFlagUsage)
+	kmsOptions := addKMSFlags(fs)
+	jasyptOptions := addJasyptFlags(fs)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s template [flags] -source <config file> <template>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 || len(sources) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	outputDir := fs.NArg() > 1
+	if info, err := os.Stat(*output); err == nil && info.IsDir() {
+		outputDir = true
+	}
+	if outputDir && *output == "-" {
+		exitOnError("several templates require an output directory, use -o flag")
+	}
+
+	valueDecryptors, err := newValueDecryptors(fs, *config, sources[0], *keyFile, keyring, kmsOptions, jasyptOptions)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+	valueDecryptor := decryptor.NewSchemeDecryptor(valueDecryptors.schemes)
+	props := make(properties.Properties)
+	for _, source := range sources {
+		content, err := ioutil.ReadFile(source)
+		if err != nil {
+			exitOnError("source read file error: %v", err)
+		}
+		sourceProps, err := templates.ReadSource(source, content, valueDecryptor)
+		if err != nil {
+			exitOnError("%v", err)
+		}
+		for key, value := range sourceProps {
+			props[key] = value
+		}
+	}
+	renderer, err := templates.NewRenderer(props, valueDecryptor)
+	if err != nil {
+		exitOnError("%v", err)
+	}
+
+	for _, name := range fs.Args() {
+		text, err := ioutil.ReadFile(name)
+		if err != nil {
+			exitOnError("template read file error: %v", err)
+		}
+		var out bytes.Buffer
+		if err = renderer.Render(&out, name, string(text)); err != nil {
+			exitOnError("%v", err)
+		}
+		outputFile := *output
+		if outputDir {
+			outputFile = filepath.Join(*output, templateOutputName(name))
+		}
+		err = writeSecretFile(outputFile, out.Bytes())
+		decryptor.Zero(out.Bytes())
+		if err != nil {
+			exitOnError("output write error: %v", err)
+		}
+	}
+}
+
+// templateOutputName is the base name of the template without template extension.
+func templateOutputName(name string) string {
+	name = filepath.Base(name)
+	for _, extension := range templateExtensions {
+		if strings.HasSuffix(name, extension) && len(name) > len(extension) {
+			return strings.TrimSuffix(name, extension)
+		}
+	}
+	return name
+}
+
+// writeSecretFile writes the data readable by the owner only, also if the file already exists.
+func writeSecretFile(name string, data []byte) error {
+	if name == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := writeKeyFile(name, data, 0600, true); err != nil {
+		return err
+	}
+	return os.Chmod(name, 0600)
+}